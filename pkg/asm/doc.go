@@ -0,0 +1,3 @@
+// Package asm defines the x86-64 instructions output by the code generator,
+// and manages writing them as GNU assembly.
+package asm
@@ -0,0 +1,452 @@
+package asm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Object is an assembled program.
+type Object struct {
+	// Text contains the encoded machine code.
+	Text []byte
+
+	// Symbols contains the labels defined in the program.
+	Symbols []Symbol
+}
+
+// Symbol is a label defined at an offset into the assembled text.
+type Symbol struct {
+	Name   string
+	Offset int
+	Global bool
+}
+
+// Assemble encodes the program into x86-64 machine code.
+//
+// Branches and calls always use 32-bit relative displacements, so symbols
+// are resolved in a single pass once all instructions have been encoded.
+func Assemble(prog *Program) (*Object, error) {
+	e := &encoder{
+		labels: make(map[Sym]int),
+	}
+	for _, instr := range prog.Text {
+		if err := e.encode(instr); err != nil {
+			return nil, fmt.Errorf("%s: %w", instr, err)
+		}
+	}
+
+	for _, f := range e.fixups {
+		target, ok := e.labels[f.sym]
+		if !ok {
+			return nil, fmt.Errorf("undefined symbol: %s", f.sym)
+		}
+		// The displacement is relative to the end of the instruction, which
+		// always ends with the displacement.
+		rel := target - (f.offset + 4)
+		binary.LittleEndian.PutUint32(e.buf[f.offset:], uint32(int32(rel)))
+	}
+
+	globals := make(map[string]bool, len(prog.Globals))
+	for _, global := range prog.Globals {
+		if _, ok := e.labels[Sym(global)]; !ok {
+			return nil, fmt.Errorf("undefined global: %s", global)
+		}
+		globals[global] = true
+	}
+
+	obj := &Object{
+		Text: e.buf,
+	}
+	for _, sym := range e.order {
+		obj.Symbols = append(obj.Symbols, Symbol{
+			Name:   string(sym),
+			Offset: e.labels[sym],
+			Global: globals[string(sym)],
+		})
+	}
+	return obj, nil
+}
+
+// fixup is a 32-bit relative displacement to a symbol that must be patched
+// once all symbols are known.
+type fixup struct {
+	offset int
+	sym    Sym
+}
+
+type encoder struct {
+	buf []byte
+
+	labels map[Sym]int
+	// order contains the labels in the order they are defined.
+	order  []Sym
+	fixups []fixup
+}
+
+// ALU opcodes for the 'op r/m, reg' form, and the ModRM extension used for
+// the immediate form.
+var aluOps = map[Op]struct {
+	opcode byte
+	ext    byte
+}{
+	ADD: {0x01, 0},
+	OR:  {0x09, 1},
+	AND: {0x21, 4},
+	SUB: {0x29, 5},
+	XOR: {0x31, 6},
+	CMP: {0x39, 7},
+}
+
+// ModRM extensions for the unary group (0xf7).
+var unaryExts = map[Op]byte{
+	NOT:  2,
+	NEG:  3,
+	DIV:  6,
+	IDIV: 7,
+}
+
+// ModRM extensions for the shift group (0xc1/0xd3).
+var shiftExts = map[Op]byte{
+	SHL: 4,
+	SHR: 5,
+	SAR: 7,
+}
+
+var condCodes = [...]byte{
+	CondE:  0x4,
+	CondNE: 0x5,
+	CondL:  0xc,
+	CondLE: 0xe,
+	CondG:  0xf,
+	CondGE: 0xd,
+	CondB:  0x2,
+	CondBE: 0x6,
+	CondA:  0x7,
+	CondAE: 0x3,
+}
+
+func (e *encoder) encode(instr Instr) error {
+	switch instr.Op {
+	case LABEL:
+		sym := instr.Dst.(Sym)
+		if _, ok := e.labels[sym]; ok {
+			return fmt.Errorf("duplicate symbol")
+		}
+		e.labels[sym] = len(e.buf)
+		e.order = append(e.order, sym)
+		return nil
+
+	case MOV:
+		switch src := instr.Src.(type) {
+		case Reg:
+			return e.encodeRM(instr.Size, opcode8(instr.Size, 0x88, 0x89), src, true, instr.Dst)
+		case Mem:
+			dst, ok := instr.Dst.(Reg)
+			if !ok {
+				return errOperands
+			}
+			return e.encodeRM(instr.Size, opcode8(instr.Size, 0x8a, 0x8b), dst, true, src)
+		case Imm:
+			if dst, ok := instr.Dst.(Reg); ok && instr.Size == S64 && !isInt32(int64(src)) {
+				// movabs.
+				e.emitRex(true, 0, false, dst, false)
+				e.buf = append(e.buf, 0xb8+byte(dst&7))
+				e.buf = binary.LittleEndian.AppendUint64(e.buf, uint64(src))
+				return nil
+			}
+			if err := e.encodeRM(instr.Size, opcode8(instr.Size, 0xc6, 0xc7), 0, false, instr.Dst); err != nil {
+				return err
+			}
+			return e.emitImm(instr.Size, int64(src))
+		}
+		return errOperands
+
+	case MOVSX, MOVZX:
+		dst, ok := instr.Dst.(Reg)
+		if !ok {
+			return errOperands
+		}
+		var opcode []byte
+		switch {
+		case instr.Op == MOVSX && instr.Size == S8:
+			opcode = []byte{0x0f, 0xbe}
+		case instr.Op == MOVSX && instr.Size == S16:
+			opcode = []byte{0x0f, 0xbf}
+		case instr.Op == MOVSX && instr.Size == S32:
+			opcode = []byte{0x63}
+		case instr.Op == MOVZX && instr.Size == S8:
+			opcode = []byte{0x0f, 0xb6}
+		case instr.Op == MOVZX && instr.Size == S16:
+			opcode = []byte{0x0f, 0xb7}
+		default:
+			return errOperands
+		}
+		return e.encodeModRM(modRM{
+			rexW:     true,
+			opcode:   opcode,
+			reg:      byte(dst),
+			rm:       instr.Src,
+			rmIsByte: instr.Size == S8,
+		})
+
+	case LEA:
+		dst, ok := instr.Dst.(Reg)
+		if !ok {
+			return errOperands
+		}
+		if _, ok := instr.Src.(Mem); !ok {
+			return errOperands
+		}
+		return e.encodeRM(instr.Size, []byte{0x8d}, dst, true, instr.Src)
+
+	case ADD, SUB, AND, OR, XOR, CMP:
+		alu := aluOps[instr.Op]
+		switch src := instr.Src.(type) {
+		case Reg:
+			return e.encodeRM(instr.Size, opcode8(instr.Size, alu.opcode-1, alu.opcode), src, true, instr.Dst)
+		case Mem:
+			dst, ok := instr.Dst.(Reg)
+			if !ok {
+				return errOperands
+			}
+			return e.encodeRM(instr.Size, opcode8(instr.Size, alu.opcode+1, alu.opcode+2), dst, true, src)
+		case Imm:
+			if instr.Size != S8 && isInt8(int64(src)) {
+				if err := e.encodeRM(instr.Size, []byte{0x83}, Reg(alu.ext), false, instr.Dst); err != nil {
+					return err
+				}
+				return e.emitImm(S8, int64(src))
+			}
+			if err := e.encodeRM(instr.Size, opcode8(instr.Size, 0x80, 0x81), Reg(alu.ext), false, instr.Dst); err != nil {
+				return err
+			}
+			return e.emitImm(instr.Size, int64(src))
+		}
+		return errOperands
+
+	case TEST:
+		switch src := instr.Src.(type) {
+		case Reg:
+			return e.encodeRM(instr.Size, opcode8(instr.Size, 0x84, 0x85), src, true, instr.Dst)
+		case Imm:
+			if err := e.encodeRM(instr.Size, opcode8(instr.Size, 0xf6, 0xf7), 0, false, instr.Dst); err != nil {
+				return err
+			}
+			return e.emitImm(instr.Size, int64(src))
+		}
+		return errOperands
+
+	case IMUL:
+		dst, ok := instr.Dst.(Reg)
+		if !ok || instr.Size == S8 {
+			return errOperands
+		}
+		return e.encodeRM(instr.Size, []byte{0x0f, 0xaf}, dst, true, instr.Src)
+
+	case NEG, NOT, DIV, IDIV:
+		return e.encodeRM(instr.Size, opcode8(instr.Size, 0xf6, 0xf7), Reg(unaryExts[instr.Op]), false, instr.Dst)
+
+	case CQO:
+		e.buf = append(e.buf, 0x48, 0x99)
+		return nil
+
+	case SHL, SHR, SAR:
+		ext := Reg(shiftExts[instr.Op])
+		switch src := instr.Src.(type) {
+		case Reg:
+			if src != RCX {
+				return errOperands
+			}
+			return e.encodeRM(instr.Size, opcode8(instr.Size, 0xd2, 0xd3), ext, false, instr.Dst)
+		case Imm:
+			if err := e.encodeRM(instr.Size, opcode8(instr.Size, 0xc0, 0xc1), ext, false, instr.Dst); err != nil {
+				return err
+			}
+			return e.emitImm(S8, int64(src))
+		}
+		return errOperands
+
+	case SET:
+		return e.encodeRM(S8, []byte{0x0f, 0x90 + condCodes[instr.Cond]}, 0, false, instr.Dst)
+
+	case J:
+		e.buf = append(e.buf, 0x0f, 0x80+condCodes[instr.Cond])
+		return e.emitRel(instr.Dst)
+	case JMP:
+		e.buf = append(e.buf, 0xe9)
+		return e.emitRel(instr.Dst)
+	case CALL:
+		e.buf = append(e.buf, 0xe8)
+		return e.emitRel(instr.Dst)
+	case RET:
+		e.buf = append(e.buf, 0xc3)
+		return nil
+
+	case PUSH, POP:
+		reg, ok := instr.Dst.(Reg)
+		if !ok {
+			return errOperands
+		}
+		// Push and pop default to 64-bit operands so don't need REX.W.
+		e.emitRex(false, 0, false, reg, false)
+		if instr.Op == PUSH {
+			e.buf = append(e.buf, 0x50+byte(reg&7))
+		} else {
+			e.buf = append(e.buf, 0x58+byte(reg&7))
+		}
+		return nil
+
+	case SYSCALL:
+		e.buf = append(e.buf, 0x0f, 0x05)
+		return nil
+
+	default:
+		return fmt.Errorf("unsupported instruction")
+	}
+}
+
+var errOperands = fmt.Errorf("unsupported operands")
+
+// modRM describes an instruction using a ModRM byte.
+type modRM struct {
+	prefix16 bool
+	rexW     bool
+	opcode   []byte
+
+	// reg is the register or opcode extension in the ModRM reg field.
+	reg byte
+	// regIsByte indicates reg is a byte register.
+	regIsByte bool
+
+	// rm is the register or memory operand in the ModRM rm field.
+	rm Operand
+	// rmIsByte indicates rm is a byte register.
+	rmIsByte bool
+}
+
+// encodeRM encodes an instruction with the given operand size, where reg is
+// either a register (if isReg) or an opcode extension.
+func (e *encoder) encodeRM(size Size, opcode []byte, reg Reg, isReg bool, rm Operand) error {
+	return e.encodeModRM(modRM{
+		prefix16:  size == S16,
+		rexW:      size == S64,
+		opcode:    opcode,
+		reg:       byte(reg),
+		regIsByte: isReg && size == S8,
+		rm:        rm,
+		rmIsByte:  size == S8,
+	})
+}
+
+func (e *encoder) encodeModRM(m modRM) error {
+	if m.prefix16 {
+		e.buf = append(e.buf, 0x66)
+	}
+
+	switch rm := m.rm.(type) {
+	case Reg:
+		e.emitRex(m.rexW, m.reg, m.regIsByte, rm, m.rmIsByte)
+		e.buf = append(e.buf, m.opcode...)
+		e.buf = append(e.buf, 0xc0|(m.reg&7)<<3|byte(rm&7))
+		return nil
+
+	case Mem:
+		e.emitRex(m.rexW, m.reg, m.regIsByte, rm.Base, false)
+		e.buf = append(e.buf, m.opcode...)
+
+		var mod byte
+		switch {
+		case rm.Disp == 0 && rm.Base&7 != RBP:
+			// RBP and R13 have no displacement-free form.
+			mod = 0x00
+		case isInt8(int64(rm.Disp)):
+			mod = 0x40
+		default:
+			mod = 0x80
+		}
+		e.buf = append(e.buf, mod|(m.reg&7)<<3|byte(rm.Base&7))
+		if rm.Base&7 == RSP {
+			// RSP and R12 require a SIB byte.
+			e.buf = append(e.buf, 0x24)
+		}
+		switch mod {
+		case 0x40:
+			e.buf = append(e.buf, byte(int8(rm.Disp)))
+		case 0x80:
+			e.buf = binary.LittleEndian.AppendUint32(e.buf, uint32(rm.Disp))
+		}
+		return nil
+
+	default:
+		return errOperands
+	}
+}
+
+// emitRex emits a REX prefix if required.
+//
+// A REX prefix is required for 64-bit operands, extended registers and to
+// access the low byte of RSP, RBP, RSI and RDI.
+func (e *encoder) emitRex(w bool, reg byte, regIsByte bool, rm Reg, rmIsByte bool) {
+	var rex byte
+	if w {
+		rex |= 0x48
+	}
+	if reg >= 8 {
+		rex |= 0x44
+	}
+	if rm >= 8 {
+		rex |= 0x41
+	}
+	if (regIsByte && 4 <= reg && reg < 8) || (rmIsByte && RSP <= rm && rm <= RDI) {
+		rex |= 0x40
+	}
+	if rex != 0 {
+		e.buf = append(e.buf, rex)
+	}
+}
+
+// emitImm emits an immediate of the given size. 64-bit operations only
+// support sign extended 32-bit immediates.
+func (e *encoder) emitImm(size Size, v int64) error {
+	switch size {
+	case S8:
+		e.buf = append(e.buf, byte(v))
+	case S16:
+		e.buf = binary.LittleEndian.AppendUint16(e.buf, uint16(v))
+	case S32:
+		e.buf = binary.LittleEndian.AppendUint32(e.buf, uint32(v))
+	default:
+		if !isInt32(v) {
+			return fmt.Errorf("immediate out of range")
+		}
+		e.buf = binary.LittleEndian.AppendUint32(e.buf, uint32(v))
+	}
+	return nil
+}
+
+func (e *encoder) emitRel(target Operand) error {
+	sym, ok := target.(Sym)
+	if !ok {
+		return errOperands
+	}
+	e.fixups = append(e.fixups, fixup{
+		offset: len(e.buf),
+		sym:    sym,
+	})
+	e.buf = append(e.buf, 0, 0, 0, 0)
+	return nil
+}
+
+// opcode8 returns the byte opcode for 8-bit operands and the full opcode
+// otherwise.
+func opcode8(size Size, op8 byte, op byte) []byte {
+	if size == S8 {
+		return []byte{op8}
+	}
+	return []byte{op}
+}
+
+func isInt8(v int64) bool {
+	return math.MinInt8 <= v && v <= math.MaxInt8
+}
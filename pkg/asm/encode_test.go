@@ -0,0 +1,212 @@
+package asm
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// The expected encodings match GNU as, except where GNU as picks a shorter
+// equivalent form, such as 'b8 imm32' for 'movl $60, %eax' rather than
+// 'c7 /0 imm32'.
+func TestAssemble_Encoding(t *testing.T) {
+	tests := []struct {
+		instr Instr
+		want  string
+	}{
+		// MOV register forms, all sizes and REX combinations.
+		{Instr{Op: MOV, Size: S64, Src: RCX, Dst: RAX}, "4889c8"},
+		{Instr{Op: MOV, Size: S32, Src: RCX, Dst: RAX}, "89c8"},
+		{Instr{Op: MOV, Size: S16, Src: RCX, Dst: RAX}, "6689c8"},
+		{Instr{Op: MOV, Size: S8, Src: RCX, Dst: RAX}, "88c8"},
+		{Instr{Op: MOV, Size: S64, Src: R8, Dst: RAX}, "4c89c0"},
+		{Instr{Op: MOV, Size: S64, Src: RAX, Dst: R15}, "4989c7"},
+		{Instr{Op: MOV, Size: S32, Src: R9, Dst: R10}, "4589ca"},
+		{Instr{Op: MOV, Size: S8, Src: RSI, Dst: RAX}, "4088f0"},
+		{Instr{Op: MOV, Size: S8, Src: RAX, Dst: RDI}, "4088c7"},
+		{Instr{Op: MOV, Size: S8, Src: R8, Dst: RAX}, "4488c0"},
+		// MOV memory forms.
+		{Instr{Op: MOV, Size: S64, Src: RAX, Dst: Mem{Base: RBP, Disp: -8}}, "488945f8"},
+		{Instr{Op: MOV, Size: S64, Src: Mem{Base: RBP, Disp: -8}, Dst: RAX}, "488b45f8"},
+		{Instr{Op: MOV, Size: S32, Src: Mem{Base: RBP, Disp: -200}, Dst: RCX}, "8b8d38ffffff"},
+		{Instr{Op: MOV, Size: S16, Src: RDX, Dst: Mem{Base: RAX, Disp: 0}}, "668910"},
+		{Instr{Op: MOV, Size: S8, Src: Mem{Base: RAX, Disp: 3}, Dst: RDX}, "8a5003"},
+		{Instr{Op: MOV, Size: S64, Src: Mem{Base: RSP, Disp: 0}, Dst: RDI}, "488b3c24"},
+		{Instr{Op: MOV, Size: S64, Src: Mem{Base: RSP, Disp: 16}, Dst: RAX}, "488b442410"},
+		{Instr{Op: MOV, Size: S64, Src: Mem{Base: R12, Disp: 0}, Dst: RAX}, "498b0424"},
+		{Instr{Op: MOV, Size: S64, Src: Mem{Base: R13, Disp: 0}, Dst: RAX}, "498b4500"},
+		{Instr{Op: MOV, Size: S64, Src: Mem{Base: RBP, Disp: 0}, Dst: RAX}, "488b4500"},
+		{Instr{Op: MOV, Size: S64, Src: R11, Dst: Mem{Base: R14, Disp: 1000}}, "4d899ee8030000"},
+		{Instr{Op: MOV, Size: S8, Src: RSI, Dst: Mem{Base: RBP, Disp: -1}}, "408875ff"},
+		{Instr{Op: MOV, Size: S64, Src: Mem{Base: RBP, Disp: 127}, Dst: RAX}, "488b457f"},
+		{Instr{Op: MOV, Size: S64, Src: Mem{Base: RBP, Disp: 128}, Dst: RAX}, "488b8580000000"},
+		{Instr{Op: MOV, Size: S64, Src: Mem{Base: RBP, Disp: -128}, Dst: RAX}, "488b4580"},
+		{Instr{Op: MOV, Size: S64, Src: Mem{Base: RBP, Disp: -129}, Dst: RAX}, "488b857fffffff"},
+		// MOV immediates.
+		{Instr{Op: MOV, Size: S64, Src: Imm(1), Dst: RAX}, "48c7c001000000"},
+		{Instr{Op: MOV, Size: S64, Src: Imm(-1), Dst: RAX}, "48c7c0ffffffff"},
+		{Instr{Op: MOV, Size: S32, Src: Imm(60), Dst: RAX}, "c7c03c000000"},
+		{Instr{Op: MOV, Size: S16, Src: Imm(0x1234), Dst: RAX}, "66c7c03412"},
+		{Instr{Op: MOV, Size: S8, Src: Imm(1), Dst: Mem{Base: RBP, Disp: -16}}, "c645f001"},
+		{Instr{Op: MOV, Size: S64, Src: Imm(0x7fffffff), Dst: R9}, "49c7c1ffffff7f"},
+		{Instr{Op: MOV, Size: S64, Src: Imm(0x80000000), Dst: RAX}, "48b80000008000000000"},
+		{Instr{Op: MOV, Size: S64, Src: Imm(-0x80000001), Dst: RCX}, "48b9ffffff7fffffffff"},
+		{Instr{Op: MOV, Size: S64, Src: Imm(0x123456789abcdef0), Dst: R10}, "49baf0debc9a78563412"},
+		{Instr{Op: MOV, Size: S32, Src: Imm(5), Dst: Mem{Base: RSP, Disp: 8}}, "c744240805000000"},
+		// Extensions.
+		{Instr{Op: MOVSX, Size: S8, Src: RAX, Dst: RAX}, "480fbec0"},
+		{Instr{Op: MOVSX, Size: S16, Src: RCX, Dst: RDX}, "480fbfd1"},
+		{Instr{Op: MOVSX, Size: S32, Src: RAX, Dst: RAX}, "4863c0"},
+		{Instr{Op: MOVSX, Size: S8, Src: RSI, Dst: R8}, "4c0fbec6"},
+		{Instr{Op: MOVZX, Size: S8, Src: RAX, Dst: RAX}, "480fb6c0"},
+		{Instr{Op: MOVZX, Size: S16, Src: Mem{Base: RBP, Disp: -2}, Dst: RAX}, "480fb745fe"},
+		{Instr{Op: MOVZX, Size: S8, Src: Mem{Base: RAX, Disp: 0}, Dst: R11}, "4c0fb618"},
+		{Instr{Op: MOVSX, Size: S32, Src: Mem{Base: RBP, Disp: -4}, Dst: RAX}, "486345fc"},
+		// LEA.
+		{Instr{Op: LEA, Size: S64, Src: Mem{Base: RBP, Disp: -32}, Dst: RAX}, "488d45e0"},
+		{Instr{Op: LEA, Size: S64, Src: Mem{Base: RAX, Disp: 8}, Dst: RDI}, "488d7808"},
+		{Instr{Op: LEA, Size: S64, Src: Mem{Base: RSP, Disp: 0}, Dst: RSI}, "488d3424"},
+		// ALU.
+		{Instr{Op: ADD, Size: S64, Src: RCX, Dst: RAX}, "4801c8"},
+		{Instr{Op: ADD, Size: S32, Src: RCX, Dst: RAX}, "01c8"},
+		{Instr{Op: ADD, Size: S16, Src: RCX, Dst: RAX}, "6601c8"},
+		{Instr{Op: ADD, Size: S8, Src: RCX, Dst: RAX}, "00c8"},
+		{Instr{Op: SUB, Size: S64, Src: Imm(8), Dst: RSP}, "4883ec08"},
+		{Instr{Op: SUB, Size: S64, Src: Imm(1024), Dst: RSP}, "4881ec00040000"},
+		{Instr{Op: ADD, Size: S64, Src: Imm(-128), Dst: RAX}, "4883c080"},
+		{Instr{Op: ADD, Size: S8, Src: Imm(1), Dst: RAX}, "80c001"},
+		{Instr{Op: ADD, Size: S16, Src: Imm(300), Dst: RCX}, "6681c12c01"},
+		{Instr{Op: AND, Size: S64, Src: RDX, Dst: R8}, "4921d0"},
+		{Instr{Op: OR, Size: S32, Src: Mem{Base: RBP, Disp: -4}, Dst: RAX}, "0b45fc"},
+		{Instr{Op: XOR, Size: S32, Src: RAX, Dst: RAX}, "31c0"},
+		{Instr{Op: XOR, Size: S64, Src: Imm(0xff), Dst: RAX}, "4881f0ff000000"},
+		{Instr{Op: CMP, Size: S64, Src: RCX, Dst: RAX}, "4839c8"},
+		{Instr{Op: CMP, Size: S8, Src: Imm(0), Dst: Mem{Base: RBP, Disp: -24}}, "807de800"},
+		{Instr{Op: CMP, Size: S32, Src: Imm(100000), Dst: RAX}, "81f8a0860100"},
+		{Instr{Op: CMP, Size: S8, Src: RDI, Dst: RSI}, "4038fe"},
+		{Instr{Op: TEST, Size: S64, Src: RAX, Dst: RAX}, "4885c0"},
+		{Instr{Op: TEST, Size: S8, Src: RAX, Dst: RAX}, "84c0"},
+		{Instr{Op: TEST, Size: S32, Src: Imm(1), Dst: RCX}, "f7c101000000"},
+		{Instr{Op: IMUL, Size: S64, Src: RCX, Dst: RAX}, "480fafc1"},
+		{Instr{Op: IMUL, Size: S32, Src: Mem{Base: RBP, Disp: -8}, Dst: R9}, "440faf4df8"},
+		{Instr{Op: IMUL, Size: S16, Src: RCX, Dst: RAX}, "660fafc1"},
+		// Unary.
+		{Instr{Op: NEG, Size: S64, Dst: RAX}, "48f7d8"},
+		{Instr{Op: NEG, Size: S8, Dst: RAX}, "f6d8"},
+		{Instr{Op: NOT, Size: S32, Dst: RCX}, "f7d1"},
+		{Instr{Op: NOT, Size: S64, Dst: R12}, "49f7d4"},
+		{Instr{Op: IDIV, Size: S64, Dst: RCX}, "48f7f9"},
+		{Instr{Op: IDIV, Size: S32, Dst: RCX}, "f7f9"},
+		{Instr{Op: DIV, Size: S64, Dst: RCX}, "48f7f1"},
+		{Instr{Op: DIV, Size: S8, Dst: RCX}, "f6f1"},
+		{Instr{Op: CQO}, "4899"},
+		// Shifts.
+		{Instr{Op: SHL, Size: S64, Src: RCX, Dst: RAX}, "48d3e0"},
+		{Instr{Op: SHR, Size: S32, Src: RCX, Dst: RAX}, "d3e8"},
+		{Instr{Op: SAR, Size: S8, Src: RCX, Dst: RAX}, "d2f8"},
+		{Instr{Op: SHL, Size: S64, Src: Imm(3), Dst: RAX}, "48c1e003"},
+		{Instr{Op: SAR, Size: S16, Src: Imm(15), Dst: RDX}, "66c1fa0f"},
+		{Instr{Op: SHR, Size: S64, Src: Imm(1), Dst: R10}, "49c1ea01"},
+		// Conditions.
+		{Instr{Op: SET, Cond: CondE, Dst: RAX}, "0f94c0"},
+		{Instr{Op: SET, Cond: CondNE, Dst: RCX}, "0f95c1"},
+		{Instr{Op: SET, Cond: CondL, Dst: RAX}, "0f9cc0"},
+		{Instr{Op: SET, Cond: CondLE, Dst: RAX}, "0f9ec0"},
+		{Instr{Op: SET, Cond: CondG, Dst: RAX}, "0f9fc0"},
+		{Instr{Op: SET, Cond: CondGE, Dst: RAX}, "0f9dc0"},
+		{Instr{Op: SET, Cond: CondB, Dst: RAX}, "0f92c0"},
+		{Instr{Op: SET, Cond: CondBE, Dst: RAX}, "0f96c0"},
+		{Instr{Op: SET, Cond: CondA, Dst: RSI}, "400f97c6"},
+		{Instr{Op: SET, Cond: CondAE, Dst: R9}, "410f93c1"},
+		// Stack and system.
+		{Instr{Op: PUSH, Dst: RBP}, "55"},
+		{Instr{Op: PUSH, Dst: R12}, "4154"},
+		{Instr{Op: POP, Dst: RAX}, "58"},
+		{Instr{Op: POP, Dst: R15}, "415f"},
+		{Instr{Op: RET}, "c3"},
+		{Instr{Op: SYSCALL}, "0f05"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.instr.String(), func(t *testing.T) {
+			obj, err := Assemble(&Program{Text: []Instr{tt.instr}})
+			if err != nil {
+				t.Fatalf("%s: %s", tt.instr, err)
+			}
+			if got := hex.EncodeToString(obj.Text); got != tt.want {
+				t.Errorf("%s: got %s, want %s", tt.instr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssemble_Symbols(t *testing.T) {
+	prog := &Program{
+		Globals: []string{"start"},
+		Text: []Instr{
+			{Op: LABEL, Dst: Sym("start")},
+			{Op: JMP, Dst: Sym("end")},
+			{Op: J, Cond: CondNE, Dst: Sym("start")},
+			{Op: CALL, Dst: Sym("f")},
+			{Op: LABEL, Dst: Sym("end")},
+			{Op: RET},
+			{Op: LABEL, Dst: Sym("f")},
+			{Op: RET},
+		},
+	}
+	obj, err := Assemble(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Displacements are relative to the end of the instruction, so the
+	// backward jump is -11.
+	want := "e90b000000" + "0f85f5ffffff" + "e801000000" + "c3" + "c3"
+	if got := hex.EncodeToString(obj.Text); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	wantSyms := []Symbol{
+		{Name: "start", Offset: 0, Global: true},
+		{Name: "end", Offset: 16},
+		{Name: "f", Offset: 17},
+	}
+	if len(obj.Symbols) != len(wantSyms) {
+		t.Fatalf("got %d symbols, want %d", len(obj.Symbols), len(wantSyms))
+	}
+	for i, sym := range obj.Symbols {
+		if sym != wantSyms[i] {
+			t.Errorf("got symbol %+v, want %+v", sym, wantSyms[i])
+		}
+	}
+}
+
+func TestAssemble_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		prog *Program
+	}{
+		{"undefined symbol", &Program{Text: []Instr{
+			{Op: CALL, Dst: Sym("missing")},
+		}}},
+		{"duplicate symbol", &Program{Text: []Instr{
+			{Op: LABEL, Dst: Sym("f")},
+			{Op: LABEL, Dst: Sym("f")},
+		}}},
+		{"undefined global", &Program{Globals: []string{"main"}}},
+		{"immediate out of range", &Program{Text: []Instr{
+			{Op: ADD, Size: S64, Src: Imm(1 << 40), Dst: RAX},
+		}}},
+		{"memory to memory", &Program{Text: []Instr{
+			{Op: MOV, Size: S64, Src: Mem{Base: RBP, Disp: -8}, Dst: Mem{Base: RBP, Disp: -16}},
+		}}},
+		{"shift by register other than cl", &Program{Text: []Instr{
+			{Op: SHL, Size: S64, Src: RDX, Dst: RAX},
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Assemble(tt.prog); err == nil {
+				t.Error("got nil error")
+			}
+		})
+	}
+}
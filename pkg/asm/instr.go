@@ -0,0 +1,168 @@
+package asm
+
+// Operand is an instruction operand, either a [Reg], [Imm], [Mem] or [Sym].
+type Operand interface {
+	operand()
+}
+
+// Imm is an immediate value.
+type Imm int64
+
+func (i Imm) operand() {}
+
+// Mem is a memory operand addressed relative to a base register.
+type Mem struct {
+	Base Reg
+	Disp int32
+}
+
+func (m Mem) operand() {}
+
+// Sym is a reference to a symbol, such as a function or branch label.
+type Sym string
+
+func (s Sym) operand() {}
+
+// Op is an instruction opcode.
+type Op int
+
+const (
+	// LABEL is a pseudo-instruction defining the symbol in Dst at the
+	// current location.
+	LABEL Op = iota
+
+	MOV
+	// MOVSX sign extends a Size source to a 64-bit destination register.
+	MOVSX
+	// MOVZX zero extends a Size source to a 64-bit destination register.
+	MOVZX
+	LEA
+
+	ADD
+	SUB
+	IMUL
+	AND
+	OR
+	XOR
+	CMP
+	TEST
+
+	NEG
+	NOT
+	IDIV
+	DIV
+	// CQO sign extends RAX into RDX:RAX.
+	CQO
+
+	SHL
+	SHR
+	SAR
+
+	// SET sets the byte register in Dst if Cond holds.
+	SET
+	// J jumps to Dst if Cond holds.
+	J
+	JMP
+	CALL
+	RET
+
+	PUSH
+	POP
+
+	SYSCALL
+)
+
+var opStrs = [...]string{
+	MOV:   "mov",
+	MOVSX: "movs",
+	MOVZX: "movz",
+	LEA:   "lea",
+
+	ADD:  "add",
+	SUB:  "sub",
+	IMUL: "imul",
+	AND:  "and",
+	OR:   "or",
+	XOR:  "xor",
+	CMP:  "cmp",
+	TEST: "test",
+
+	NEG:  "neg",
+	NOT:  "not",
+	IDIV: "idiv",
+	DIV:  "div",
+	CQO:  "cqto",
+
+	SHL: "shl",
+	SHR: "shr",
+	SAR: "sar",
+
+	SET:  "set",
+	J:    "j",
+	JMP:  "jmp",
+	CALL: "call",
+	RET:  "ret",
+
+	PUSH: "push",
+	POP:  "pop",
+
+	SYSCALL: "syscall",
+}
+
+func (op Op) String() string {
+	return opStrs[op]
+}
+
+// Cond is a condition code used by [SET] and [J].
+type Cond int
+
+const (
+	CondE  Cond = iota // Equal.
+	CondNE             // Not equal.
+	CondL              // Less (signed).
+	CondLE             // Less or equal (signed).
+	CondG              // Greater (signed).
+	CondGE             // Greater or equal (signed).
+	CondB              // Below (unsigned).
+	CondBE             // Below or equal (unsigned).
+	CondA              // Above (unsigned).
+	CondAE             // Above or equal (unsigned).
+)
+
+var condStrs = [...]string{
+	CondE:  "e",
+	CondNE: "ne",
+	CondL:  "l",
+	CondLE: "le",
+	CondG:  "g",
+	CondGE: "ge",
+	CondB:  "b",
+	CondBE: "be",
+	CondA:  "a",
+	CondAE: "ae",
+}
+
+func (c Cond) String() string {
+	return condStrs[c]
+}
+
+// Instr is a single x86-64 instruction.
+//
+// Operands follow AT&T ordering, so the source comes before the destination.
+// Instructions with a single operand only set Dst.
+type Instr struct {
+	Op   Op
+	Cond Cond
+	Size Size
+
+	Src Operand
+	Dst Operand
+}
+
+// Program is an assembled list of instructions.
+type Program struct {
+	// Globals contains the symbols exported from the program.
+	Globals []string
+
+	Text []Instr
+}
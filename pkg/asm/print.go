@@ -0,0 +1,91 @@
+package asm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// Fprint writes the program to the writer as GNU assembly.
+func Fprint(w io.Writer, prog *Program) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "\t.text")
+	for _, global := range prog.Globals {
+		fmt.Fprintf(bw, "\t.globl %s\n", global)
+	}
+	for _, instr := range prog.Text {
+		if instr.Op == LABEL {
+			fmt.Fprintf(bw, "%s:\n", instr.Dst.(Sym))
+			continue
+		}
+		fmt.Fprintf(bw, "\t%s\n", instr.String())
+	}
+
+	return bw.Flush()
+}
+
+func (instr Instr) String() string {
+	switch instr.Op {
+	case LABEL:
+		return fmt.Sprintf("%s:", instr.Dst.(Sym))
+	case RET, CQO, SYSCALL:
+		return instr.Op.String()
+	case JMP, CALL:
+		return fmt.Sprintf("%s %s", instr.Op, formatOperand(instr.Dst, S64))
+	case J:
+		return fmt.Sprintf("j%s %s", instr.Cond, formatOperand(instr.Dst, S64))
+	case SET:
+		return fmt.Sprintf("set%s %s", instr.Cond, formatOperand(instr.Dst, S8))
+	case MOVSX, MOVZX:
+		return fmt.Sprintf(
+			"%s%sq %s, %s",
+			instr.Op, instr.Size.Suffix(),
+			formatOperand(instr.Src, instr.Size),
+			formatOperand(instr.Dst, S64),
+		)
+	case SHL, SHR, SAR:
+		if _, ok := instr.Src.(Reg); ok {
+			// Shifts by a register always use CL.
+			return fmt.Sprintf(
+				"%s%s %%cl, %s",
+				instr.Op, instr.Size.Suffix(),
+				formatOperand(instr.Dst, instr.Size),
+			)
+		}
+	case MOV:
+		if imm, ok := instr.Src.(Imm); ok && instr.Size == S64 && !isInt32(int64(imm)) {
+			return fmt.Sprintf("movabsq %s, %s", formatOperand(imm, S64), formatOperand(instr.Dst, S64))
+		}
+	}
+
+	var operands []string
+	if instr.Src != nil {
+		operands = append(operands, formatOperand(instr.Src, instr.Size))
+	}
+	if instr.Dst != nil {
+		operands = append(operands, formatOperand(instr.Dst, instr.Size))
+	}
+	return fmt.Sprintf("%s%s %s", instr.Op, instr.Size.Suffix(), strings.Join(operands, ", "))
+}
+
+func formatOperand(operand Operand, size Size) string {
+	switch operand := operand.(type) {
+	case Reg:
+		return "%" + operand.Name(size)
+	case Imm:
+		return fmt.Sprintf("$%d", operand)
+	case Mem:
+		return fmt.Sprintf("%d(%%%s)", operand.Disp, operand.Base)
+	case Sym:
+		return string(operand)
+	default:
+		return fmt.Sprintf("<unknown operand %#v>", operand)
+	}
+}
+
+func isInt32(v int64) bool {
+	return math.MinInt32 <= v && v <= math.MaxInt32
+}
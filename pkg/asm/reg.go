@@ -0,0 +1,88 @@
+package asm
+
+// Reg is a general purpose x86-64 register.
+//
+// Registers are ordered by their encoding number.
+type Reg int
+
+const (
+	RAX Reg = iota
+	RCX
+	RDX
+	RBX
+	RSP
+	RBP
+	RSI
+	RDI
+	R8
+	R9
+	R10
+	R11
+	R12
+	R13
+	R14
+	R15
+)
+
+var regStrs = [...][4]string{
+	RAX: {"al", "ax", "eax", "rax"},
+	RCX: {"cl", "cx", "ecx", "rcx"},
+	RDX: {"dl", "dx", "edx", "rdx"},
+	RBX: {"bl", "bx", "ebx", "rbx"},
+	RSP: {"spl", "sp", "esp", "rsp"},
+	RBP: {"bpl", "bp", "ebp", "rbp"},
+	RSI: {"sil", "si", "esi", "rsi"},
+	RDI: {"dil", "di", "edi", "rdi"},
+	R8:  {"r8b", "r8w", "r8d", "r8"},
+	R9:  {"r9b", "r9w", "r9d", "r9"},
+	R10: {"r10b", "r10w", "r10d", "r10"},
+	R11: {"r11b", "r11w", "r11d", "r11"},
+	R12: {"r12b", "r12w", "r12d", "r12"},
+	R13: {"r13b", "r13w", "r13d", "r13"},
+	R14: {"r14b", "r14w", "r14d", "r14"},
+	R15: {"r15b", "r15w", "r15d", "r15"},
+}
+
+// Name returns the name of the register when accessed with the given size.
+func (r Reg) Name(size Size) string {
+	switch size {
+	case S8:
+		return regStrs[r][0]
+	case S16:
+		return regStrs[r][1]
+	case S32:
+		return regStrs[r][2]
+	default:
+		return regStrs[r][3]
+	}
+}
+
+func (r Reg) String() string {
+	return r.Name(S64)
+}
+
+func (r Reg) operand() {}
+
+// Size is the size of an operand in bytes.
+type Size int
+
+const (
+	S8  Size = 1
+	S16 Size = 2
+	S32 Size = 4
+	S64 Size = 8
+)
+
+// Suffix returns the GNU instruction suffix for the size.
+func (s Size) Suffix() string {
+	switch s {
+	case S8:
+		return "b"
+	case S16:
+		return "w"
+	case S32:
+		return "l"
+	default:
+		return "q"
+	}
+}
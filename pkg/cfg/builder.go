@@ -0,0 +1,165 @@
+package cfg
+
+import (
+	"github.com/andydunstall/nova/pkg/syntax"
+)
+
+// target contains the blocks targeted by break and continue statements in
+// a loop.
+type target struct {
+	// label is the loop label, or nil if the loop isn't labeled.
+	label *syntax.Ident
+	// brk is the block following the loop.
+	brk *Block
+	// cont is the block evaluating the post expression, if any, at the end
+	// of each iteration.
+	cont *Block
+}
+
+type builder struct {
+	cfg *CFG
+	// current is the block statements are added to. After a statement that
+	// doesn't fall through, such as a return, current is a new block with
+	// no predecessors.
+	current *Block
+	// targets contains the enclosing loops, innermost last.
+	targets []target
+}
+
+func (b *builder) stmtList(list []syntax.Stmt) {
+	for _, stmt := range list {
+		b.stmt(stmt)
+	}
+}
+
+func (b *builder) stmt(stmt syntax.Stmt) {
+	b.cfg.stmts[stmt] = b.current
+
+	switch stmt := stmt.(type) {
+	case *syntax.DeclStmt, *syntax.ExprStmt:
+		b.add(stmt)
+	case *syntax.ReturnStmt:
+		b.add(stmt)
+		b.current = b.newBlock()
+	case *syntax.BlockStmt:
+		b.stmtList(stmt.List)
+	case *syntax.IfStmt:
+		b.ifStmt(stmt)
+	case *syntax.LoopStmt:
+		b.loop(stmt.Label, stmt.Cond != nil, stmt.Cond, stmt.Body, nil)
+	case *syntax.ForStmt:
+		if stmt.Init != nil {
+			b.stmt(stmt.Init)
+		}
+		b.loop(stmt.Label, stmt.Cond != nil, stmt.Cond, stmt.Body, stmt.Post)
+	case *syntax.RangeStmt:
+		b.add(stmt.Low)
+		b.add(stmt.High)
+		// The loop variable is compared to the upper bound before each
+		// iteration, so the loop may exit.
+		b.loop(stmt.Label, true, nil, stmt.Body, nil)
+	case *syntax.BreakStmt:
+		b.add(stmt)
+		if t, ok := b.target(stmt.Label); ok {
+			b.jump(t.brk)
+			b.current = b.newBlock()
+		}
+	case *syntax.ContinueStmt:
+		b.add(stmt)
+		if t, ok := b.target(stmt.Label); ok {
+			b.jump(t.cont)
+			b.current = b.newBlock()
+		}
+	}
+}
+
+func (b *builder) ifStmt(stmt *syntax.IfStmt) {
+	b.add(stmt.Cond)
+	cond := b.current
+
+	then := b.newBlock()
+	done := b.newBlock()
+	b.edge(cond, then)
+	b.current = then
+	b.stmt(stmt.Then)
+	b.jump(done)
+
+	if stmt.Else == nil {
+		b.edge(cond, done)
+	} else {
+		els := b.newBlock()
+		b.edge(cond, els)
+		b.current = els
+		b.stmt(stmt.Else)
+		b.jump(done)
+	}
+	b.current = done
+}
+
+// loop adds a loop. If hasCond is set the loop may exit before each
+// iteration, otherwise it only exits with a break. cond and post may be
+// nil.
+func (b *builder) loop(label *syntax.Ident, hasCond bool, cond syntax.Expr, body *syntax.BlockStmt, post syntax.Expr) {
+	head := b.newBlock()
+	bodyBlock := b.newBlock()
+	next := b.newBlock()
+	done := b.newBlock()
+
+	b.jump(head)
+	b.current = head
+	if cond != nil {
+		b.add(cond)
+	}
+	if hasCond {
+		b.edge(head, done)
+	}
+	b.jump(bodyBlock)
+
+	b.targets = append(b.targets, target{label: label, brk: done, cont: next})
+	b.current = bodyBlock
+	b.stmtList(body.List)
+	b.targets = b.targets[:len(b.targets)-1]
+	b.jump(next)
+
+	b.current = next
+	if post != nil {
+		b.add(post)
+	}
+	b.jump(head)
+
+	b.current = done
+}
+
+// target returns the loop targeted by a break or continue with the given
+// label, or the innermost loop if the label is nil.
+func (b *builder) target(label *syntax.Ident) (target, bool) {
+	for i := len(b.targets) - 1; i >= 0; i-- {
+		t := b.targets[i]
+		if label == nil || (t.label != nil && t.label.Name == label.Name) {
+			return t, true
+		}
+	}
+	// The type checker reports break and continue outside a loop, so
+	// the statement is treated as falling through rather than making the
+	// following code unreachable.
+	return target{}, false
+}
+
+func (b *builder) add(node syntax.Node) {
+	b.current.Nodes = append(b.current.Nodes, node)
+}
+
+// jump adds an edge from the current block to the given block.
+func (b *builder) jump(to *Block) {
+	b.edge(b.current, to)
+}
+
+func (b *builder) edge(from, to *Block) {
+	from.Succs = append(from.Succs, to)
+}
+
+func (b *builder) newBlock() *Block {
+	block := &Block{Index: len(b.cfg.Blocks)}
+	b.cfg.Blocks = append(b.cfg.Blocks, block)
+	return block
+}
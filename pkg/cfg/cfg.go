@@ -0,0 +1,125 @@
+package cfg
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/andydunstall/nova/pkg/syntax"
+)
+
+// Block is a basic block, containing nodes that are executed in order
+// before branching to one of its successors.
+type Block struct {
+	// Index is the index of the block in [CFG.Blocks].
+	Index int
+	// Nodes contains the simple statements and expressions executed by the
+	// block, such as an [syntax.ExprStmt] or the condition of an
+	// [syntax.IfStmt]. Statements containing other statements, such as
+	// loops, are represented by the blocks they create instead.
+	Nodes []syntax.Node
+	// Succs contains the blocks that may execute after this block. A block
+	// ending with a return statement has no successors.
+	Succs []*Block
+	// Live is set if the block is reachable from the entry block.
+	Live bool
+}
+
+// CFG is the control flow graph of a function body.
+type CFG struct {
+	// Blocks contains all blocks in the graph. The entry block is first.
+	Blocks []*Block
+	// Exit is the block reached by the end of the function body, so if it's
+	// live, the function may end without a return statement.
+	Exit *Block
+
+	// stmts maps each statement to the block it starts in.
+	stmts map[syntax.Stmt]*Block
+	body  *syntax.BlockStmt
+}
+
+// New builds the control flow graph of a function body.
+func New(body *syntax.BlockStmt) *CFG {
+	b := &builder{
+		cfg: &CFG{
+			stmts: make(map[syntax.Stmt]*Block),
+			body:  body,
+		},
+	}
+	b.current = b.newBlock()
+	b.stmtList(body.List)
+	b.cfg.Exit = b.current
+
+	b.cfg.mark(b.cfg.Blocks[0])
+	return b.cfg
+}
+
+// Unreachable returns the statements that can't be executed. Only the
+// first unreachable statement of a statement list is returned, since the
+// statements that follow it, and any nested statements, are also
+// unreachable.
+func (g *CFG) Unreachable() []syntax.Stmt {
+	var stmts []syntax.Stmt
+	g.unreachable(g.body.List, &stmts)
+	return stmts
+}
+
+func (g *CFG) unreachable(list []syntax.Stmt, stmts *[]syntax.Stmt) {
+	for _, stmt := range list {
+		if !g.stmts[stmt].Live {
+			*stmts = append(*stmts, stmt)
+			return
+		}
+
+		switch stmt := stmt.(type) {
+		case *syntax.BlockStmt:
+			g.unreachable(stmt.List, stmts)
+		case *syntax.IfStmt:
+			g.unreachable([]syntax.Stmt{stmt.Then}, stmts)
+			if stmt.Else != nil {
+				g.unreachable([]syntax.Stmt{stmt.Else}, stmts)
+			}
+		case *syntax.LoopStmt:
+			g.unreachable(stmt.Body.List, stmts)
+		case *syntax.ForStmt:
+			g.unreachable(stmt.Body.List, stmts)
+		case *syntax.RangeStmt:
+			g.unreachable(stmt.Body.List, stmts)
+		}
+	}
+}
+
+// String returns a description of the graph for debugging, listing each
+// block with its nodes and successors.
+func (g *CFG) String() string {
+	var b strings.Builder
+	for _, block := range g.Blocks {
+		fmt.Fprintf(&b, "block %d", block.Index)
+		if !block.Live {
+			b.WriteString(" (dead)")
+		}
+		if block == g.Exit {
+			b.WriteString(" (exit)")
+		}
+		b.WriteString(":\n")
+		for _, node := range block.Nodes {
+			fmt.Fprintf(&b, "\t%s: %T\n", node.Pos(), node)
+		}
+		var succs []string
+		for _, succ := range block.Succs {
+			succs = append(succs, fmt.Sprint(succ.Index))
+		}
+		fmt.Fprintf(&b, "\tsuccs: [%s]\n", strings.Join(succs, " "))
+	}
+	return b.String()
+}
+
+// mark marks the blocks reachable from the given block as live.
+func (g *CFG) mark(block *Block) {
+	if block.Live {
+		return
+	}
+	block.Live = true
+	for _, succ := range block.Succs {
+		g.mark(succ)
+	}
+}
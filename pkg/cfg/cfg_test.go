@@ -0,0 +1,112 @@
+package cfg_test
+
+import (
+	"testing"
+
+	"github.com/andydunstall/nova/pkg/cfg"
+	"github.com/andydunstall/nova/pkg/lex"
+	"github.com/andydunstall/nova/pkg/syntax"
+)
+
+func TestNew_Exit(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		live bool
+	}{
+		{"empty", "", true},
+		{"return", "return 1;", false},
+		{"if", "if (c) {\n\treturn 1;\n}", true},
+		{"if else", "if (c) {\n\treturn 1;\n} else {\n\treturn 2;\n}", false},
+		{"loop", "loop (c) {\n\treturn 1;\n}", true},
+		{"infinite loop", "loop {\n}", false},
+		{"infinite loop with break", "loop {\n\tbreak;\n}", true},
+		{"infinite loop with nested break", "loop {\n\tloop {\n\t\tbreak;\n\t}\n}", false},
+		{"labeled break", "a: loop {\n\tloop {\n\t\tbreak a;\n\t}\n}", true},
+		{"labeled continue", "a: loop {\n\tloop {\n\t\tcontinue a;\n\t}\n}", false},
+		{"for", "for (;;) {\n}", false},
+		{"for with condition", "for (; c;) {\n}", true},
+		{"range", "for i in 0..n {\n\treturn 1;\n}", true},
+		{"break outside loop", "break;", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := cfg.New(parseBody(t, tt.body))
+			if g.Exit.Live != tt.live {
+				t.Errorf("got exit live %t, want %t\n%s", g.Exit.Live, tt.live, g)
+			}
+		})
+	}
+}
+
+func TestCFG_Unreachable(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		// lines are the lines of the unreachable statements.
+		lines []int
+	}{
+		{"none", "let x = 1;\nreturn x;", nil},
+		{"after return", "return 1;\nlet x = 1;\nlet y = 2;", []int{3}},
+		{"after break", "loop {\n\tbreak;\n\tlet x = 1;\n}", []int{4}},
+		{"after continue", "loop (c) {\n\tcontinue;\n\tlet x = 1;\n}", []int{4}},
+		{"after infinite loop", "loop {\n}\nreturn 1;", []int{4}},
+		{"after labeled break", "a: loop {\n\tloop {\n\t\tbreak a;\n\t\tlet x = 1;\n\t}\n\tlet y = 2;\n}", []int{5, 7}},
+		{"in both branches", "if (c) {\n\treturn 1;\n\tlet x = 1;\n} else {\n\treturn 2;\n\tlet y = 2;\n}", []int{4, 7}},
+		{"after if else", "if (c) {\n\treturn 1;\n} else {\n\treturn 2;\n}\nreturn 3;", []int{7}},
+		{"nested in unreachable", "return 1;\nif (c) {\n\tlet x = 1;\n}", []int{3}},
+		{"after post", "for (let mut i = 0; i < 2; i = i + 1) {\n\treturn 1;\n}\nreturn 2;", nil},
+		{"break outside loop", "break;\nreturn 1;", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := cfg.New(parseBody(t, tt.body))
+			var lines []int
+			for _, stmt := range g.Unreachable() {
+				lines = append(lines, stmt.Pos().Line)
+			}
+			if len(lines) != len(tt.lines) {
+				t.Fatalf("got unreachable lines %v, want %v\n%s", lines, tt.lines, g)
+			}
+			for i := range lines {
+				if lines[i] != tt.lines[i] {
+					t.Fatalf("got unreachable lines %v, want %v\n%s", lines, tt.lines, g)
+				}
+			}
+		})
+	}
+}
+
+func TestCFG_String(t *testing.T) {
+	g := cfg.New(parseBody(t, "let x = 1;\nif (c) {\n\treturn 1;\n}\nreturn x;"))
+	want := `block 0:
+	test.nv:2:1: *syntax.DeclStmt
+	test.nv:3:5: *syntax.VarExpr
+	succs: [1 2]
+block 1:
+	test.nv:4:2: *syntax.ReturnStmt
+	succs: []
+block 2:
+	test.nv:6:1: *syntax.ReturnStmt
+	succs: []
+block 3 (dead):
+	succs: [2]
+block 4 (dead) (exit):
+	succs: []
+`
+	if got := g.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// parseBody parses the statements as the body of a function.
+func parseBody(t *testing.T, body string) *syntax.BlockStmt {
+	t.Helper()
+
+	src := "fn f() {\n" + body + "\n}\n"
+	file, err := syntax.Parse(lex.NewScanner("test.nv", []byte(src)))
+	if err != nil {
+		t.Fatalf("parse: %s", err)
+	}
+	return file.Decls[0].(*syntax.FuncDecl).Body
+}
@@ -0,0 +1,4 @@
+// Package cfg builds control flow graphs of Nova function bodies, used to
+// find code that can't be reached, functions that may end without
+// returning and uses of moved values.
+package cfg
@@ -2,7 +2,12 @@ package cli
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
+	"github.com/andydunstall/nova/pkg/asm"
+	"github.com/andydunstall/nova/pkg/codegen"
+	"github.com/andydunstall/nova/pkg/elf"
 	"github.com/spf13/cobra"
 )
 
@@ -10,9 +15,22 @@ func newBuildCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "build path [flags]",
 		Short: "build a Nova program",
-		Long:  `...`,
+		Long: `Build a Nova file into an x86-64 Linux executable.
+
+The executable is written to the input path with the .nv extension removed,
+such as 'nova build examples/return.nv' outputs 'examples/return'. Use
+'-o' to override the output path.`,
 	}
 
+	var output string
+	cmd.Flags().StringVarP(
+		&output, "output", "o", "",
+		"path to write the executable (defaults to the input path without the .nv extension)",
+	)
+
+	var opts frontendOptions
+	opts.register(cmd)
+
 	cmd.Run = func(_ *cobra.Command, args []string) {
 		if len(args) == 0 {
 			exitError(fmt.Errorf("build: missing path"))
@@ -21,7 +39,7 @@ func newBuildCommand() *cobra.Command {
 			exitError(fmt.Errorf("build: only one path is supported"))
 		}
 
-		if err := runBuild(args[0]); err != nil {
+		if err := runBuild(args[0], output, opts); err != nil {
 			exitError(fmt.Errorf("build: %w", err))
 		}
 	}
@@ -29,7 +47,56 @@ func newBuildCommand() *cobra.Command {
 	return cmd
 }
 
-func runBuild(path string) error {
-	// TODO(andydunstall): Build end to end (to output a binary).
-	panic("not implemented")
+func runBuild(path string, output string, opts frontendOptions) error {
+	if output == "" {
+		if !strings.HasSuffix(path, ".nv") {
+			return fmt.Errorf("%s: missing .nv extension (use -o to set the output path)", path)
+		}
+		output = strings.TrimSuffix(path, ".nv")
+	}
+
+	syntaxAST, typeInfo, err := check(path, opts)
+	if err != nil {
+		return err
+	}
+
+	// Phase 3: Code generation.
+
+	prog, err := codegen.Generate(syntaxAST, typeInfo)
+	if err != nil {
+		return fmt.Errorf("codegen: %w", err)
+	}
+
+	// Phase 4: Assemble and link.
+
+	obj, err := asm.Assemble(prog)
+	if err != nil {
+		return fmt.Errorf("assemble: %w", err)
+	}
+
+	elfFile := &elf.File{
+		Text:  obj.Text,
+		Entry: "_start",
+	}
+	for _, sym := range obj.Symbols {
+		elfFile.Symbols = append(elfFile.Symbols, elf.Symbol{
+			Name:   sym.Name,
+			Offset: uint64(sym.Offset),
+			Global: sym.Global,
+		})
+	}
+
+	f, err := os.OpenFile(output, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o755)
+	if err != nil {
+		return fmt.Errorf("write: %s: %w", output, err)
+	}
+	if err := elf.WriteExecutable(f, elfFile); err != nil {
+		f.Close()
+		return fmt.Errorf("write: %s: %w", output, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write: %s: %w", output, err)
+	}
+
+	return nil
 }
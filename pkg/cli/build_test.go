@@ -0,0 +1,965 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+func TestBuild_Examples(t *testing.T) {
+	tests := []struct {
+		name string
+		exit int
+	}{
+		{"functions", 30},
+		{"loops", 5},
+		{"methods", 6},
+		{"pointers", 7},
+		{"return", 10},
+		{"structs", 16},
+		{"types", 15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join("..", "..", "examples", tt.name+".nv")
+			if got := buildAndRunPath(t, path); got != tt.exit {
+				t.Errorf("%s: got exit code %d, want %d", path, got, tt.exit)
+			}
+		})
+	}
+}
+
+// buildAndRun builds the source into an executable, runs it and returns
+// its exit code.
+func buildAndRun(t *testing.T, src string) int {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test.nv")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return buildAndRunPath(t, path)
+}
+
+func buildAndRunPath(t *testing.T, path string) int {
+	t.Helper()
+
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		t.Skip("executables only run on linux/amd64")
+	}
+
+	output := filepath.Join(t.TempDir(), "out")
+	if err := runBuild(path, output, frontendOptions{DiagnosticsFormat: "text"}); err != nil {
+		t.Fatalf("build: %s", err)
+	}
+
+	err := exec.Command(output).Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	if err != nil {
+		t.Fatalf("run: %s", err)
+	}
+	return 0
+}
+
+func TestBuild_Calls(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		exit int
+	}{
+		{"stack arguments", `
+fn f(a: i64, b: i64, c: i64, d: i64, e: i64, f: i64, g: i64, h: i64) -> i64 {
+	return a + 2 * b + 3 * c + 4 * d + 5 * e + 6 * f + 7 * g + 8 * h;
+}
+
+fn main() -> i64 {
+	return f(1, 1, 1, 1, 1, 1, 1, 2);
+}
+`, 44},
+		{"mixed sizes", `
+fn f(a: u8, b: i32, c: i64, d: u16, e: i8, f: u32, g: u8, h: i16) -> i64 {
+	return i64(a) + i64(b) + c + i64(d) + i64(e) + i64(f) + i64(g) + i64(h);
+}
+
+fn main() -> i64 {
+	return f(1, 2, 3, 4, -5, 6, 7, -8);
+}
+`, 10},
+		{"nested calls", `
+fn add(a: i32, b: i32, c: i32, d: i32, e: i32, f: i32, g: i32) -> i32 {
+	return a + b + c + d + e + f + g;
+}
+
+fn main() -> i32 {
+	return add(1, add(1, 1, 1, 1, 1, 1, 1), 1, 1, 1, 1, add(0, 0, 0, 0, 0, 0, 3));
+}
+`, 15},
+		{"recursion", `
+fn fib(n: i32) -> i32 {
+	if (n < 2) {
+		return n;
+	}
+	return fib(n - 1) + fib(n - 2);
+}
+
+fn main() -> i32 {
+	return fib(10);
+}
+`, 55},
+		{"struct return", `
+struct P {
+	a i64
+	b i32
+	c u8
+};
+
+fn make(a: i64, b: i32, c: u8) -> P {
+	return P{a: a, b: b, c: c};
+}
+
+fn main() -> i64 {
+	let p: P = make(1, 20, 3);
+	return p.a + i64(p.b) + i64(p.c);
+}
+`, 24},
+		{"struct argument", `
+struct P {
+	a i64
+	b i64
+};
+
+fn sum(x: i64, p: P, y: i64, q: P) -> i64 {
+	return x + p.a + 2 * p.b + y + q.a * q.b;
+}
+
+fn main() -> i64 {
+	return sum(1, P{a: 2, b: 3}, 4, P{a: 5, b: 6});
+}
+`, 43},
+		{"struct return with stack arguments", `
+struct P {
+	a i64
+	b i64
+};
+
+fn make(a: i64, b: i64, c: i64, d: i64, e: i64, f: i64, g: i64) -> P {
+	return P{a: a + b + c, b: d + e + f + g};
+}
+
+fn main() -> i64 {
+	let p: P = make(1, 2, 3, 4, 5, 6, 7);
+	return p.a * 10 + p.b;
+}
+`, 82},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildAndRun(t, tt.src); got != tt.exit {
+				t.Errorf("got exit code %d, want %d", got, tt.exit)
+			}
+		})
+	}
+}
+
+func TestBuild_MaxErrors(t *testing.T) {
+	src := `
+fn main() -> i32 {
+	return +;
+	return +;
+	return +;
+}
+`
+	path := filepath.Join(t.TempDir(), "test.nv")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		max  int
+		errs int
+	}{
+		{0, 3},
+		{1, 1},
+		{2, 2},
+		{10, 3},
+	}
+	for _, tt := range tests {
+		opts := frontendOptions{MaxErrors: tt.max, DiagnosticsFormat: "json"}
+		err := runBuild(path, filepath.Join(t.TempDir(), "out"), opts)
+		want := fmt.Sprintf("aborting due to %d previous errors", tt.errs)
+		if tt.errs == 1 {
+			want = "aborting due to previous error"
+		}
+		if err == nil || err.Error() != want {
+			t.Errorf("max %d: got error %v, want %q", tt.max, err, want)
+		}
+	}
+}
+
+func TestBuild_IntegerLiterals(t *testing.T) {
+	tests := []struct {
+		expr string
+		exit int
+	}{
+		{"0x2a", 42},
+		{"0XfF - 0b1111_0000", 15},
+		{"0o17 + 0O1", 16},
+		{"1_000 - 9_9_0", 10},
+		{"0x1_0000_0000 / 0x1000_0000", 16},
+		{"0xffff_ffff_ffff_ffff - 0xffff_ffff_ffff_fff0", 15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			src := "fn main() -> u64 {\n\treturn " + tt.expr + ";\n}\n"
+			if got := buildAndRun(t, src); got != tt.exit {
+				t.Errorf("got exit code %d, want %d", got, tt.exit)
+			}
+		})
+	}
+}
+
+func TestBuild_Methods(t *testing.T) {
+	src := `
+struct Acc {
+	total i64
+	scale i64
+};
+
+fn Acc::new(scale: i64) -> Acc {
+	return Acc{total: 0, scale: scale};
+}
+
+fn Acc::add(mut self, a: i64, b: i64, c: i64, d: i64, e: i64, f: i64) {
+	self.total = self.total + self.scale * (a + b + c + d + e + f);
+}
+
+fn Acc::scaled(n: i64) -> i64 {
+	return self.scale * n;
+}
+
+fn main() -> i64 {
+	let mut acc: Acc = Acc::new(2);
+	acc.add(1, 1, 1, 1, 1, 1);
+	acc.add(0, 0, 0, 0, 0, acc.scaled(5));
+	return acc.total;
+}
+`
+	// The receiver is passed by reference, so add updates acc.
+	if got := buildAndRun(t, src); got != 32 {
+		t.Errorf("got exit code %d, want 32", got)
+	}
+}
+
+func TestBuild_Destructors(t *testing.T) {
+	// Values with destructors are moved through calls, returns and
+	// variables, and destroyed at scope exit, without changing the values
+	// that remain in use.
+	src := `
+struct R {
+	id i64
+	n i64
+};
+
+fn R::delete() {
+	self.id = 0;
+	self.n = 0;
+}
+
+fn make(id: i64, n: i64) -> R {
+	let r: R = R{id: id, n: n};
+	return r;
+}
+
+fn pass(r: R) -> R {
+	return r;
+}
+
+fn sum(a: R, b: R) -> i64 {
+	return a.id * a.n + b.id * b.n;
+}
+
+fn main() -> i64 {
+	let mut total: i64 = 0;
+	let mut i: i64 = 0;
+	loop (i < 3) {
+		let a: R = make(i, 2);
+		let b: R = pass(make(1, 5));
+		total = total + sum(a, b);
+		{
+			let c: R = make(9, 9);
+		}
+		i = i + 1;
+	}
+	let d: R = make(2, 3);
+	if (total > 0) {
+		return total + d.n;
+	}
+	return 0;
+}
+`
+	if got := buildAndRun(t, src); got != 24 {
+		t.Errorf("got exit code %d, want 24", got)
+	}
+}
+
+func TestBuild_DestructorOrder(t *testing.T) {
+	// Each destructor appends its ID to the log as a decimal digit, so the
+	// log records the order values are destroyed in.
+	const prelude = `
+struct R {
+	id i32
+	log *mut i32
+};
+
+fn R::delete() {
+	let log: *mut i32 = self.log;
+	*log = *log * 10 + self.id;
+}
+
+struct Pair {
+	first R
+	second R
+	id i32
+	log *mut i32
+};
+
+fn Pair::delete() {
+	let log: *mut i32 = self.log;
+	*log = *log * 10 + self.id;
+}
+
+fn make(id: i32, log: *mut i32) -> R {
+	return R{id: id, log: log};
+}
+
+fn consume(r: R) {
+}
+
+fn early(log: *mut i32) {
+	let a: R = make(1, log);
+	loop {
+		let b: R = make(2, log);
+		return;
+	}
+}
+`
+
+	tests := []struct {
+		name string
+		body string
+		log  int
+	}{
+		{"reverse order", `
+	{
+		let a: R = make(1, log);
+		let b: R = make(2, log);
+		let c: R = make(3, log);
+	}`, 321},
+		{"fields after destructor", `
+	{
+		let p: Pair = Pair{first: make(1, log), second: make(2, log), id: 3, log: log};
+	}`, 321},
+		{"moved", `
+	{
+		let a: R = make(1, log);
+		let b: R = make(2, log);
+		consume(a);
+		*log = *log * 10 + 3;
+	}`, 132},
+		{"moved on one path", `
+	let mut i: i32 = 0;
+	loop (i < 2) {
+		let a: R = make(i + 1, log);
+		if (i == 0) {
+			consume(a);
+			*log = *log * 10 + 5;
+		}
+		i = i + 1;
+	}`, 152},
+		{"return", `
+	let a: R = make(1, log);
+	{
+		let b: R = make(2, log);
+		if (1 == 1) {
+			let c: R = make(3, log);
+			return 0;
+		}
+	}
+	*log = 9;`, 321},
+		{"break", `
+	let a: R = make(1, log);
+	loop (1 == 1) {
+		let b: R = make(2, log);
+		{
+			let c: R = make(3, log);
+			break;
+		}
+	}
+	*log = *log * 10 + 4;`, 3241},
+		{"continue", `
+	let a: R = make(1, log);
+	let mut i: i32 = 0;
+	loop (i < 2) {
+		let b: R = make(2, log);
+		i = i + 1;
+		if (i == 1) {
+			let c: R = make(3, log);
+			continue;
+		}
+		*log = *log * 10 + 4;
+	}`, 32421},
+		{"labeled break", `
+	outer: loop (1 == 1) {
+		let a: R = make(1, log);
+		loop (1 == 1) {
+			let b: R = make(2, log);
+			break outer;
+		}
+	}
+	*log = *log * 10 + 3;`, 213},
+		{"labeled continue", `
+	let mut i: i32 = 0;
+	outer: loop (i < 2) {
+		i = i + 1;
+		let a: R = make(i, log);
+		loop (1 == 1) {
+			let b: R = make(5, log);
+			continue outer;
+		}
+	}`, 5152},
+		{"infinite loop", `
+	loop {
+		let a: R = make(1, log);
+		break;
+	}
+	*log = *log * 10 + 2;`, 12},
+		{"range loop", `
+	for i in 0..2 {
+		let a: R = make(i + 1, log);
+		if (i == 0) {
+			continue;
+		}
+		*log = *log * 10 + 5;
+	}`, 152},
+		{"bare return", `
+	early(log);
+	*log = *log * 10 + 3;`, 213},
+		{"temporary", `
+	let a: R = make(1, log);
+	make(2, log).id;
+	*log = *log * 10 + 3;`, 231},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := prelude + `
+fn run(log: *mut i32) -> i32 {` + tt.body + `
+	return 0;
+}
+
+fn main() -> i32 {
+	let mut log: i32 = 0;
+	run(&mut log);
+	if (log != ` + strconv.Itoa(tt.log) + `) {
+		return 1;
+	}
+	return 0;
+}
+`
+			if got := buildAndRun(t, src); got != 0 {
+				t.Errorf("destroyed in the wrong order, want %d", tt.log)
+			}
+		})
+	}
+}
+
+func TestBuild_Conversions(t *testing.T) {
+	tests := []string{
+		// Truncation.
+		"u8(m1) == 255",
+		"u8(big) == 0x78",
+		"i8(u8(200)) == -56",
+		"u16(big) == 0x5678",
+		"u32(big) == 0x12345678",
+		// Sign and zero extension.
+		"i64(m1) == -1",
+		"u64(m1) == 0xffff_ffff_ffff_ffff",
+		"u64(u32(m1)) == 0xffff_ffff",
+		"i64(i8(u8(200))) == -56",
+		"i32(u8(200)) == 200",
+		// bool.
+		"bool(big)",
+		"bool(m1)",
+		"!bool(zero)",
+		"bool(u8(256 + zero)) == false_",
+		"i32(true_) == 1",
+		"u8(false_) == 0",
+		"i64(true_) + i64(true_) == 2",
+	}
+	for _, tt := range tests {
+		t.Run(tt, func(t *testing.T) {
+			src := `
+fn main() -> i32 {
+	let m1: i32 = -1;
+	let zero: i32 = 0;
+	let big: i64 = 0x1234_5678;
+	let true_ = 1 == 1;
+	let false_ = 1 == 0;
+	if (` + tt + `) {
+		return 0;
+	}
+	return 1;
+}
+`
+			if got := buildAndRun(t, src); got != 0 {
+				t.Errorf("%s is false", tt)
+			}
+		})
+	}
+}
+
+func TestBuild_Labels(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		exit int
+	}{
+		{"break outer", `
+	let mut i: i32 = 0;
+	let mut j: i32 = 0;
+	outer: loop (i < 10) {
+		j = 0;
+		loop (j < 10) {
+			if (i * j == 12) {
+				break outer;
+			}
+			j = j + 1;
+		}
+		i = i + 1;
+	}
+	return i * 10 + j;`, 26},
+		{"continue outer", `
+	let mut n: i32 = 0;
+	let mut i: i32 = 0;
+	outer: loop (i < 5) {
+		i = i + 1;
+		let mut j: i32 = 0;
+		loop (j < 5) {
+			j = j + 1;
+			if (j > i) {
+				continue outer;
+			}
+			n = n + 1;
+		}
+	}
+	return n;`, 15},
+		{"break inner", `
+	let mut n: i32 = 0;
+	let mut i: i32 = 0;
+	outer: loop (i < 3) {
+		i = i + 1;
+		inner: loop (1 == 1) {
+			n = n + i;
+			break inner;
+		}
+	}
+	return n;`, 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "fn main() -> i32 {" + tt.body + "\n}\n"
+			if got := buildAndRun(t, src); got != tt.exit {
+				t.Errorf("got exit code %d, want %d", got, tt.exit)
+			}
+		})
+	}
+}
+
+func TestBuild_Loops(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		exit int
+	}{
+		{"infinite", `
+	let mut n: i32 = 0;
+	loop {
+		n = n + 1;
+		if (n == 7) {
+			break;
+		}
+	}
+	return n;`, 7},
+		{"for", `
+	let mut n: i32 = 0;
+	for (let mut i: i32 = 1; i <= 4; i = i + 1) {
+		n = n + i;
+	}
+	return n;`, 10},
+		{"for empty", `
+	let mut n: i32 = 0;
+	for (;;) {
+		n = n + 1;
+		if (n == 3) {
+			break;
+		}
+	}
+	return n;`, 3},
+		{"for continue", `
+	let mut n: i32 = 0;
+	for (let mut i: i32 = 0; i < 6; i = i + 1) {
+		if (i == 2) {
+			continue;
+		}
+		n = n + 1;
+	}
+	return n;`, 5},
+		{"range", `
+	let mut n: i32 = 0;
+	for i in 2..5 {
+		n = n * 10 + i;
+	}
+	return n % 256;`, 234 % 256},
+		{"range empty", `
+	let mut n: i32 = 0;
+	for i in 5..2 {
+		n = n + 1;
+	}
+	return n;`, 0},
+		{"range continue", `
+	let mut n: i32 = 0;
+	for i in 0..6 {
+		if (i % 2 == 0) {
+			continue;
+		}
+		n = n + i;
+	}
+	return n;`, 9},
+		{"range bound once", `
+	let mut hi: i32 = 3;
+	let mut n: i32 = 0;
+	for i in 0..hi {
+		hi = hi + 1;
+		n = n + 1;
+	}
+	return n;`, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "fn main() -> i32 {" + tt.body + "\n}\n"
+			if got := buildAndRun(t, src); got != tt.exit {
+				t.Errorf("got exit code %d, want %d", got, tt.exit)
+			}
+		})
+	}
+}
+
+func TestBuild_Operators(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		exit int
+	}{
+		{"compound", `
+	let mut x: i32 = 10;
+	x += 5;
+	x -= 3;
+	x *= 4;
+	x /= 6;
+	x %= 5;
+	return x;`, 3},
+		{"increment", `
+	let mut x: i32 = 0;
+	x++;
+	x++;
+	x--;
+	x++;
+	return x;`, 2},
+		{"bitwise", `
+	let x: i32 = 0b1100;
+	let y: i32 = 0b1010;
+	return (x & y) * 100 + (x | y) * 10 + (x ^ y);`, (8*100 + 14*10 + 6) % 256},
+		{"complement", `
+	let x: u8 = 0xf0;
+	return i32(~x);`, 0x0f},
+		{"compound bitwise", `
+	let mut x: i32 = 0xff;
+	x &= 0x3c;
+	x |= 1;
+	x ^= 0x10;
+	return x;`, 0x2d},
+		{"shift", `
+	let x: i32 = 3;
+	return (x << 4) + (x >> 1);`, 49},
+		{"compound shift", `
+	let mut x: i32 = 1;
+	x <<= 5;
+	x >>= 2;
+	return x;`, 8},
+		{"arithmetic shift", `
+	let x: i32 = -16;
+	return -(x >> 2);`, 4},
+		{"logical shift", `
+	let x: u32 = 0x80000000;
+	return i32(x >> 28);`, 8},
+		{"shift typed by context", `
+	let n: u32 = 40;
+	let x: u64 = 1 << n;
+	return i32(x >> 38);`, 4},
+		{"shift out all bits", `
+	let x: i32 = 1;
+	let n: u32 = 40;
+	return x << n;`, 0},
+		{"increment field", `
+	let mut p: P = P{x: 1};
+	p.x++;
+	p.x += 2;
+	return p.x;`, 4},
+		{"increment through pointer", `
+	let mut x: i32 = 1;
+	let q: *mut i32 = &mut x;
+	(*q)++;
+	*q <<= 2;
+	return x;`, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "struct P {\n\tx i32\n};\n\nfn main() -> i32 {" + tt.body + "\n}\n"
+			if got := buildAndRun(t, src); got != tt.exit {
+				t.Errorf("got exit code %d, want %d", got, tt.exit)
+			}
+		})
+	}
+}
+
+func TestBuild_Warnings(t *testing.T) {
+	// Warnings, such as unreachable code, don't stop the build.
+	src := "fn main() -> i32 {\n\treturn 3;\n\treturn 4;\n}\n"
+	if got := buildAndRun(t, src); got != 3 {
+		t.Errorf("got exit code %d, want 3", got)
+	}
+}
+
+func TestBuild_Returns(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		exit int
+	}{
+		{"bare return from main", "fn main() {\n\treturn;\n}\n", 0},
+		{"main without return", "fn main() {\n}\n", 0},
+		{"bare return", `fn set(p: *mut i32, v: i32) {
+	if (v > 10) {
+		return;
+	}
+	*p = v;
+}
+
+fn main() -> i32 {
+	let mut x: i32 = 1;
+	set(&mut x, 20);
+	set(&mut x, 5);
+	set(&mut x, 30);
+	return x;
+}
+`, 5},
+		{"bare return in loop", `fn count(p: *mut i32) {
+	loop {
+		*p = *p + 1;
+		if (*p == 4) {
+			return;
+		}
+	}
+}
+
+fn main() -> i32 {
+	let mut n: i32 = 0;
+	count(&mut n);
+	return n;
+}
+`, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildAndRun(t, tt.src); got != tt.exit {
+				t.Errorf("got exit code %d, want %d", got, tt.exit)
+			}
+		})
+	}
+}
+
+func TestBuild_Precedence(t *testing.T) {
+	tests := []struct {
+		expr string
+		exit int
+	}{
+		{"-a + b", 2},
+		{"-a - b", 244},
+		{"b - -a", 12},
+		{"~a & 0xff", 250},
+		{"(a & 1) + (b | 8)", 16},
+		{"1 + 2 * 3 << 1", 14},
+		{"b % a * 2", 4},
+		{"-b >> 1", 252},
+		{"*p * *p + 1", 26},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			src := "fn main() -> i32 {\n\tlet a: i32 = 5;\n\tlet b: i32 = 7;\n\tlet p: *i32 = &a;\n\treturn " + tt.expr + ";\n}\n"
+			if got := buildAndRun(t, src); got != tt.exit {
+				t.Errorf("got exit code %d, want %d", got, tt.exit)
+			}
+		})
+	}
+}
+
+func TestBuild_ShortCircuit(t *testing.T) {
+	const prelude = `
+struct R {
+	id i32
+	log *mut i32
+};
+
+fn R::delete() {
+	*self.log = *self.log * 10 + self.id;
+}
+
+fn R::ok() -> bool {
+	return 1 == 1;
+}
+
+fn make(id: i32, log: *mut i32) -> R {
+	return R{id: id, log: log};
+}
+
+fn side(p: *mut i32) -> bool {
+	*p = *p + 1;
+	return 1 == 1;
+}
+`
+
+	tests := []struct {
+		name string
+		body string
+		exit int
+	}{
+		{"and false", `
+	let mut n: i32 = 0;
+	let b = 1 == 0 && side(&mut n);
+	return n;`, 0},
+		{"and true", `
+	let mut n: i32 = 0;
+	let b = 1 == 1 && side(&mut n);
+	return n;`, 1},
+		{"or true", `
+	let mut n: i32 = 0;
+	let b = 1 == 1 || side(&mut n);
+	return n;`, 0},
+		{"or false", `
+	let mut n: i32 = 0;
+	let b = 1 == 0 || side(&mut n);
+	return n;`, 1},
+		{"condition", `
+	let mut n: i32 = 0;
+	if (1 == 0 && side(&mut n)) {
+		n += 100;
+	}
+	if (1 == 1 || side(&mut n)) {
+		n += 10;
+	}
+	return n;`, 10},
+		{"nested", `
+	let mut n: i32 = 0;
+	let b = (1 == 0 && side(&mut n)) || (side(&mut n) && side(&mut n));
+	if (!b) {
+		return 99;
+	}
+	return n;`, 2},
+		{"loop condition", `
+	let mut n: i32 = 0;
+	let mut i: i32 = 0;
+	loop (i < 3 && side(&mut n)) {
+		i += 1;
+	}
+	return n;`, 3},
+		{"null check", `
+	let p: *i32 = null;
+	if (p != null && *p > 0) {
+		return 1;
+	}
+	return 2;`, 2},
+		{"skipped temporary", `
+	let mut log: i32 = 0;
+	let b = 1 == 0 && make(5, &mut log).ok();
+	let c = 1 == 1 || make(6, &mut log).ok();
+	return log;`, 0},
+		{"evaluated temporary", `
+	let mut log: i32 = 0;
+	let b = 1 == 1 && make(5, &mut log).ok();
+	let c = 1 == 0 || make(6, &mut log).ok();
+	return log;`, 56},
+		{"temporary in loop", `
+	let mut log: i32 = 0;
+	for (let mut i: i32 = 0; i < 2; i++) {
+		let b = i == 1 && make(i + 1, &mut log).ok();
+	}
+	return log;`, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := prelude + "\nfn main() -> i32 {" + tt.body + "\n}\n"
+			if got := buildAndRun(t, src); got != tt.exit {
+				t.Errorf("got exit code %d, want %d", got, tt.exit)
+			}
+		})
+	}
+}
+
+func TestBuild_BoolLiterals(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		exit int
+	}{
+		{"true", "\n\treturn i32(true);", 1},
+		{"false", "\n\treturn i32(false);", 0},
+		{"condition", `
+	if (false) {
+		return 1;
+	}
+	if (true) {
+		return 2;
+	}
+	return 3;`, 2},
+		{"compare", `
+	let b = 1 < 2;
+	if (b == true && b != false) {
+		return 4;
+	}
+	return 5;`, 4},
+		{"loop condition", `
+	let mut n: i32 = 0;
+	loop (true) {
+		n++;
+		if (n == 6) {
+			break;
+		}
+	}
+	return n;`, 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "fn main() -> i32 {" + tt.body + "\n}\n"
+			if got := buildAndRun(t, src); got != tt.exit {
+				t.Errorf("got exit code %d, want %d", got, tt.exit)
+			}
+		})
+	}
+}
@@ -3,11 +3,10 @@ package cli
 import (
 	"fmt"
 	"os"
+	"strings"
 
-	"github.com/andydunstall/nova/pkg/lex"
-	"github.com/andydunstall/nova/pkg/print"
-	"github.com/andydunstall/nova/pkg/syntax"
-	"github.com/andydunstall/nova/pkg/types"
+	"github.com/andydunstall/nova/pkg/asm"
+	"github.com/andydunstall/nova/pkg/codegen"
 	"github.com/spf13/cobra"
 )
 
@@ -15,9 +14,22 @@ func newCompileCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "compile path [flags]",
 		Short: "compile a Nova program",
-		Long:  `...`,
+		Long: `Compile a Nova file into GNU x86-64 assembly.
+
+The assembly is written to the input path with the .nv extension replaced
+by .s, such as 'nova compile examples/return.nv' outputs 'examples/return.s'.
+Use '-o' to override the output path, or '-o -' to write to stdout.`,
 	}
 
+	var output string
+	cmd.Flags().StringVarP(
+		&output, "output", "o", "",
+		"path to write the assembly (defaults to the input path with a .s extension)",
+	)
+
+	var opts frontendOptions
+	opts.register(cmd)
+
 	cmd.Run = func(_ *cobra.Command, args []string) {
 		if len(args) == 0 {
 			exitError(fmt.Errorf("compile: missing path"))
@@ -26,7 +38,7 @@ func newCompileCommand() *cobra.Command {
 			exitError(fmt.Errorf("compile: only one path is supported"))
 		}
 
-		if err := runCompile(args[0]); err != nil {
+		if err := runCompile(args[0], output, opts); err != nil {
 			exitError(fmt.Errorf("compile: %w", err))
 		}
 	}
@@ -34,32 +46,44 @@ func newCompileCommand() *cobra.Command {
 	return cmd
 }
 
-func runCompile(path string) error {
-	src, err := os.ReadFile(path)
-	if err != nil {
-		return fmt.Errorf("read: %s: %w", path, err)
+func runCompile(path string, output string, opts frontendOptions) error {
+	if output == "" {
+		if !strings.HasSuffix(path, ".nv") {
+			return fmt.Errorf("%s: missing .nv extension (use -o to set the output path)", path)
+		}
+		output = strings.TrimSuffix(path, ".nv") + ".s"
+	}
+	if output == "-" && opts.DiagnosticsFormat == "json" {
+		// Both the assembly and JSON diagnostics are written to stdout.
+		return fmt.Errorf("-o - cannot be used with --diagnostics-format=json")
 	}
 
-	// Phase 1: Parse source into syntax AST.
-
-	scanner := lex.NewScanner(src)
-	syntaxAST, err := syntax.Parse(scanner)
+	syntaxAST, typeInfo, err := check(path, opts)
 	if err != nil {
-		return fmt.Errorf("parse syntax: %w", err)
+		return err
 	}
 
-	fmt.Println("syntax ast:")
-	print.Print(syntaxAST)
+	// Phase 3: Code generation.
 
-	// Phase 2: Type checking.
-
-	typeInfo, err := types.Check(syntaxAST)
+	prog, err := codegen.Generate(syntaxAST, typeInfo)
 	if err != nil {
-		return fmt.Errorf("types: %w", err)
+		return fmt.Errorf("codegen: %w", err)
 	}
 
-	fmt.Println("type info:")
-	print.Print(typeInfo)
+	if output == "-" {
+		return asm.Fprint(os.Stdout, prog)
+	}
 
+	f, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("write: %s: %w", output, err)
+	}
+	if err := asm.Fprint(f, prog); err != nil {
+		f.Close()
+		return fmt.Errorf("write: %s: %w", output, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write: %s: %w", output, err)
+	}
 	return nil
 }
@@ -0,0 +1,27 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompile_StdoutWithJSONDiagnostics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.nv")
+	if err := os.WriteFile(path, []byte("fn main() -> i32 {\n\treturn 1;\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both the assembly and JSON diagnostics would be written to stdout.
+	if err := runCompile(path, "-", frontendOptions{DiagnosticsFormat: "json"}); err == nil {
+		t.Error("got nil error")
+	}
+
+	output := filepath.Join(t.TempDir(), "test.s")
+	if err := runCompile(path, output, frontendOptions{DiagnosticsFormat: "json"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(output); err != nil {
+		t.Error(err)
+	}
+}
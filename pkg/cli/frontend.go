@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/andydunstall/nova/pkg/diag"
+	"github.com/andydunstall/nova/pkg/lex"
+	"github.com/andydunstall/nova/pkg/syntax"
+	"github.com/andydunstall/nova/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+// frontendOptions configures parsing, type checking and reporting
+// diagnostics.
+type frontendOptions struct {
+	MaxErrors         int
+	DiagnosticsFormat string
+}
+
+func (o *frontendOptions) register(cmd *cobra.Command) {
+	cmd.Flags().IntVar(
+		&o.MaxErrors, "max-errors", syntax.DefaultMaxErrors,
+		"maximum number of syntax errors to report (0 for unlimited)",
+	)
+	cmd.Flags().StringVar(
+		&o.DiagnosticsFormat, "diagnostics-format", "text",
+		"format of reported errors and warnings: 'text' (to stderr) or 'json' (to stdout)",
+	)
+}
+
+func (o *frontendOptions) validate() error {
+	switch o.DiagnosticsFormat {
+	case "text", "json":
+		return nil
+	default:
+		return fmt.Errorf("unsupported diagnostics format: %s", o.DiagnosticsFormat)
+	}
+}
+
+// check parses and type checks the file at the given path.
+//
+// Any diagnostics are reported in the configured format, and an error is
+// returned if the file contains errors.
+func check(path string, opts frontendOptions) (*syntax.File, *types.Info, error) {
+	if err := opts.validate(); err != nil {
+		return nil, nil, err
+	}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read: %s: %w", path, err)
+	}
+
+	// Phase 1: Parse source into syntax AST.
+
+	scanner := lex.NewScanner(path, src)
+	syntaxAST, err := syntax.Parse(scanner, syntax.WithMaxErrors(opts.MaxErrors))
+	if err != nil {
+		return nil, nil, reportDiagnostics(err, path, src, opts)
+	}
+
+	// Phase 2: Type checking.
+
+	typeInfo, err := types.Check(syntaxAST)
+	if err != nil {
+		return nil, nil, reportDiagnostics(err, path, src, opts)
+	}
+	if len(typeInfo.Warnings) > 0 {
+		if err := printDiagnostics(typeInfo.Warnings, path, src, opts); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return syntaxAST, typeInfo, nil
+}
+
+// printDiagnostics writes the diagnostics in the configured format.
+func printDiagnostics(list diag.List, path string, src []byte, opts frontendOptions) error {
+	list.Sort()
+	switch opts.DiagnosticsFormat {
+	case "json":
+		if err := diag.FprintJSON(os.Stdout, list); err != nil {
+			return fmt.Errorf("write diagnostics: %w", err)
+		}
+	default:
+		sources := map[string][]byte{path: src}
+		if err := diag.Fprint(os.Stderr, list, sources); err != nil {
+			return fmt.Errorf("write diagnostics: %w", err)
+		}
+	}
+	return nil
+}
+
+// reportDiagnostics writes the diagnostics contained in err, then returns
+// an error summarising the failure.
+func reportDiagnostics(err error, path string, src []byte, opts frontendOptions) error {
+	var list diag.List
+	if !errors.As(err, &list) {
+		return err
+	}
+
+	if err := printDiagnostics(list, path, src, opts); err != nil {
+		return err
+	}
+
+	n := 0
+	for _, d := range list {
+		if d.Severity == diag.Error {
+			n++
+		}
+	}
+	if n == 1 {
+		return fmt.Errorf("aborting due to previous error")
+	}
+	return fmt.Errorf("aborting due to %d previous errors", n)
+}
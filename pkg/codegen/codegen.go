@@ -0,0 +1,1219 @@
+package codegen
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/andydunstall/nova/pkg/asm"
+	"github.com/andydunstall/nova/pkg/assert"
+	"github.com/andydunstall/nova/pkg/lex"
+	"github.com/andydunstall/nova/pkg/syntax"
+	"github.com/andydunstall/nova/pkg/types"
+)
+
+// Generate generates the x86-64 program for the given file.
+//
+// The program includes a _start entrypoint that calls main and exits with
+// its return value.
+func Generate(file *syntax.File, info *types.Info) (*asm.Program, error) {
+	g := newGenerator(info)
+	if err := g.genFile(file); err != nil {
+		return nil, err
+	}
+	return g.prog, nil
+}
+
+// argRegs are the registers used to pass the first integer arguments, in
+// order. Any remaining arguments are passed on the stack.
+var argRegs = []asm.Reg{asm.RDI, asm.RSI, asm.RDX, asm.RCX, asm.R8, asm.R9}
+
+// loop contains the branch targets of an enclosing loop.
+type loop struct {
+	// label is the name of the loop label, or empty if unlabeled.
+	label         string
+	continueLabel asm.Sym
+	breakLabel    asm.Sym
+	// scopes is the number of scopes enclosing the loop, so jumping out of
+	// the loop body destroys the locals of the inner scopes.
+	scopes int
+}
+
+type generator struct {
+	info *types.Info
+	prog *asm.Program
+
+	// Per function state.
+	text      []asm.Instr
+	locals    map[*types.Object]*local
+	frameSize int32
+	// depth is the number of 8 byte temporaries pushed onto the stack.
+	depth    int
+	retLabel asm.Sym
+	// retPtr is the stack slot holding the address to write a struct
+	// result to, if the function returns a struct.
+	retPtr asm.Mem
+	loops  []loop
+	// scopes contains the locals of each enclosing scope that need
+	// destroying, in declaration order.
+	scopes [][]*local
+	// temps contains the temporaries of the current statement that need
+	// destroying.
+	temps []*local
+	// tempOf maps expressions to the temporary holding their value.
+	tempOf map[syntax.Expr]*local
+	// flags are the offsets of the drop flags, which are cleared on entry.
+	flags []int32
+
+	// symbols maps each function to its symbol.
+	symbols map[*types.Object]asm.Sym
+	labels  int
+}
+
+func newGenerator(info *types.Info) *generator {
+	return &generator{
+		info:    info,
+		prog:    &asm.Program{},
+		symbols: make(map[*types.Object]asm.Sym),
+	}
+}
+
+func (g *generator) genFile(file *syntax.File) error {
+	// The type checker verifies main is declared with a valid signature.
+	main := g.info.FileScope.Lookup("main")
+	g.genStart(main.Type.(*types.Func))
+
+	// Member functions are named 'Type.name', which can't conflict with
+	// other functions.
+	for _, decl := range file.Decls {
+		if decl, ok := decl.(*syntax.FuncDecl); ok {
+			sym := decl.Name.Name
+			if decl.Recv != nil {
+				sym = decl.Recv.Name + "." + sym
+			}
+			g.symbols[g.info.Defs[decl.Name]] = asm.Sym(sym)
+		}
+	}
+
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *syntax.FuncDecl:
+			if err := g.genFuncDecl(decl); err != nil {
+				return err
+			}
+		case *syntax.StructDecl:
+			// Struct layouts are computed by the type checker.
+		default:
+			return fmt.Errorf("%s: unsupported top level declaration", decl.Pos())
+		}
+	}
+	return nil
+}
+
+// genStart generates the program entrypoint, which calls main and passes
+// its result to the exit syscall.
+func (g *generator) genStart(main *types.Func) {
+	g.prog.Globals = append(g.prog.Globals, "_start")
+
+	text := []asm.Instr{
+		{Op: asm.LABEL, Dst: asm.Sym("_start")},
+		{Op: asm.CALL, Dst: asm.Sym("main")},
+	}
+	if main.Return != nil {
+		text = append(text, asm.Instr{Op: asm.MOV, Size: asm.S32, Src: asm.RAX, Dst: asm.RDI})
+	} else {
+		text = append(text, asm.Instr{Op: asm.XOR, Size: asm.S32, Src: asm.RDI, Dst: asm.RDI})
+	}
+	text = append(text,
+		// exit(2)
+		asm.Instr{Op: asm.MOV, Size: asm.S32, Src: asm.Imm(60), Dst: asm.RAX},
+		asm.Instr{Op: asm.SYSCALL},
+	)
+	g.prog.Text = append(g.prog.Text, text...)
+}
+
+// Declarations.
+
+func (g *generator) genFuncDecl(decl *syntax.FuncDecl) error {
+	g.text = nil
+	g.locals = make(map[*types.Object]*local)
+	g.frameSize = 0
+	g.depth = 0
+	g.retLabel = g.newLabel()
+	g.loops = nil
+	g.scopes = nil
+	g.temps = nil
+	g.tempOf = make(map[syntax.Expr]*local)
+	g.flags = nil
+
+	// The parameters and top level of the body share a scope.
+	g.openScope()
+
+	// A struct result is written to memory provided by the caller, whose
+	// address is passed as a hidden first argument.
+	obj := g.info.Defs[decl.Name]
+	fn := obj.Type.(*types.Func)
+	regs := argRegs
+	if isStruct(fn.Return) {
+		g.retPtr = g.alloc(8)
+		g.store(types.U64, regs[0], g.retPtr)
+		regs = regs[1:]
+	}
+	// The receiver of a method is passed by address.
+	if fn.Recv != nil {
+		g.locals[fn.Recv] = &local{
+			offset:   g.alloc(8).Disp,
+			typ:      fn.Recv.Type,
+			indirect: true,
+		}
+		g.store(types.U64, regs[0], g.locals[fn.Recv].mem())
+		regs = regs[1:]
+	}
+
+	for i, param := range decl.Params {
+		if i >= len(regs) {
+			// Stack arguments are above the return address and saved
+			// frame pointer.
+			g.declareParam(param.Name, int32(16+8*(i-len(regs))))
+			continue
+		}
+
+		// Spill register arguments onto the stack. Struct arguments are
+		// passed as the address of a copy owned by the callee.
+		if isStruct(g.info.Defs[param.Name].Type) {
+			g.declareParam(param.Name, g.alloc(8).Disp)
+			g.store(types.U64, regs[i], g.locals[g.info.Defs[param.Name]].mem())
+			continue
+		}
+		l := g.declareLocal(param.Name)
+		g.store(l.typ, regs[i], l.mem())
+	}
+	// The callee owns struct arguments, so must destroy them.
+	for _, l := range g.scopes[0] {
+		g.setFlag(l, true)
+	}
+
+	if err := g.genStmtList(decl.Body.List); err != nil {
+		return err
+	}
+	g.closeScope()
+
+	body := g.text
+	// Align the frame so the stack is 16 byte aligned at call sites.
+	frameSize := (g.frameSize + 15) &^ 15
+
+	g.prog.Text = append(g.prog.Text,
+		asm.Instr{Op: asm.LABEL, Dst: g.symbols[obj]},
+		asm.Instr{Op: asm.PUSH, Size: asm.S64, Dst: asm.RBP},
+		asm.Instr{Op: asm.MOV, Size: asm.S64, Src: asm.RSP, Dst: asm.RBP},
+	)
+	if frameSize > 0 {
+		g.prog.Text = append(g.prog.Text,
+			asm.Instr{Op: asm.SUB, Size: asm.S64, Src: asm.Imm(frameSize), Dst: asm.RSP},
+		)
+	}
+	for _, flag := range g.flags {
+		g.prog.Text = append(g.prog.Text,
+			asm.Instr{Op: asm.MOV, Size: asm.S8, Src: asm.Imm(0), Dst: asm.Mem{Base: asm.RBP, Disp: flag}},
+		)
+	}
+	g.prog.Text = append(g.prog.Text, body...)
+	g.prog.Text = append(g.prog.Text,
+		asm.Instr{Op: asm.LABEL, Dst: g.retLabel},
+		asm.Instr{Op: asm.MOV, Size: asm.S64, Src: asm.RBP, Dst: asm.RSP},
+		asm.Instr{Op: asm.POP, Size: asm.S64, Dst: asm.RBP},
+		asm.Instr{Op: asm.RET},
+	)
+	return nil
+}
+
+func (g *generator) genVarDecl(decl *syntax.VarDecl) error {
+	if err := g.genExpr(decl.Expr); err != nil {
+		return err
+	}
+	g.move(decl.Expr)
+	l := g.declareLocal(decl.Name)
+	if isStruct(l.typ) {
+		g.copyValue(l.typ, asm.Mem{Base: asm.RAX}, l.mem())
+	} else {
+		g.store(l.typ, asm.RAX, l.mem())
+	}
+	g.setFlag(l, true)
+	g.destroyTemps()
+	return nil
+}
+
+// Statements.
+
+func (g *generator) genStmt(stmt syntax.Stmt) error {
+	switch stmt := stmt.(type) {
+	case *syntax.DeclStmt:
+		decl, ok := stmt.Decl.(*syntax.VarDecl)
+		if !ok {
+			return fmt.Errorf("%s: unsupported local declaration", stmt.Pos())
+		}
+		return g.genVarDecl(decl)
+	case *syntax.ReturnStmt:
+		return g.genReturnStmt(stmt)
+	case *syntax.ExprStmt:
+		if err := g.genExpr(stmt.E); err != nil {
+			return err
+		}
+		g.destroyTemps()
+		return nil
+	case *syntax.BlockStmt:
+		return g.genBlockStmt(stmt)
+	case *syntax.IfStmt:
+		return g.genIfStmt(stmt)
+	case *syntax.LoopStmt:
+		return g.genLoopStmt(stmt)
+	case *syntax.ForStmt:
+		return g.genForStmt(stmt)
+	case *syntax.RangeStmt:
+		return g.genRangeStmt(stmt)
+	case *syntax.BreakStmt:
+		loop, ok := g.targetLoop(stmt.Label)
+		if !ok {
+			return fmt.Errorf("%s: break outside loop", stmt.Pos())
+		}
+		g.destroyScopes(loop.scopes)
+		g.emit(asm.Instr{Op: asm.JMP, Dst: loop.breakLabel})
+		return nil
+	case *syntax.ContinueStmt:
+		loop, ok := g.targetLoop(stmt.Label)
+		if !ok {
+			return fmt.Errorf("%s: continue outside loop", stmt.Pos())
+		}
+		g.destroyScopes(loop.scopes)
+		g.emit(asm.Instr{Op: asm.JMP, Dst: loop.continueLabel})
+		return nil
+	default:
+		return fmt.Errorf("%s: unsupported statement", stmt.Pos())
+	}
+}
+
+func (g *generator) genBlockStmt(stmt *syntax.BlockStmt) error {
+	g.openScope()
+	if err := g.genStmtList(stmt.List); err != nil {
+		return err
+	}
+	g.closeScope()
+	return nil
+}
+
+func (g *generator) genStmtList(list []syntax.Stmt) error {
+	for _, stmt := range list {
+		if err := g.genStmt(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// genScopedStmt generates the statement in its own scope, matching the
+// scopes of the type checker.
+func (g *generator) genScopedStmt(stmt syntax.Stmt) error {
+	if block, ok := stmt.(*syntax.BlockStmt); ok {
+		return g.genBlockStmt(block)
+	}
+
+	g.openScope()
+	if err := g.genStmt(stmt); err != nil {
+		return err
+	}
+	g.closeScope()
+	return nil
+}
+
+func (g *generator) genReturnStmt(stmt *syntax.ReturnStmt) error {
+	if stmt.Result != nil {
+		if err := g.genExpr(stmt.Result); err != nil {
+			return err
+		}
+		g.move(stmt.Result)
+		if typ := g.info.TypeOf(stmt.Result); isStruct(typ) {
+			// Copy the result to the caller and return its address.
+			g.emit(asm.Instr{Op: asm.MOV, Size: asm.S64, Src: g.retPtr, Dst: asm.RCX})
+			g.copyValue(typ, asm.Mem{Base: asm.RAX}, asm.Mem{Base: asm.RCX})
+			g.emit(asm.Instr{Op: asm.MOV, Size: asm.S64, Src: asm.RCX, Dst: asm.RAX})
+		}
+	}
+	g.destroyTemps()
+	g.destroyScopes(0)
+	g.emit(asm.Instr{Op: asm.JMP, Dst: g.retLabel})
+	return nil
+}
+
+func (g *generator) genIfStmt(stmt *syntax.IfStmt) error {
+	elseLabel := g.newLabel()
+	end := g.newLabel()
+
+	if err := g.genExpr(stmt.Cond); err != nil {
+		return err
+	}
+	g.destroyTemps()
+	g.emit(asm.Instr{Op: asm.TEST, Size: asm.S64, Src: asm.RAX, Dst: asm.RAX})
+	g.emit(asm.Instr{Op: asm.J, Cond: asm.CondE, Dst: elseLabel})
+
+	if err := g.genScopedStmt(stmt.Then); err != nil {
+		return err
+	}
+	g.emit(asm.Instr{Op: asm.JMP, Dst: end})
+
+	g.emit(asm.Instr{Op: asm.LABEL, Dst: elseLabel})
+	if stmt.Else != nil {
+		if err := g.genScopedStmt(stmt.Else); err != nil {
+			return err
+		}
+	}
+	g.emit(asm.Instr{Op: asm.LABEL, Dst: end})
+	return nil
+}
+
+func (g *generator) genLoopStmt(stmt *syntax.LoopStmt) error {
+	return g.genLoop(stmt.Label, g.condBranch(stmt.Cond), stmt.Body, nil)
+}
+
+func (g *generator) genForStmt(stmt *syntax.ForStmt) error {
+	g.openScope()
+	if stmt.Init != nil {
+		if err := g.genStmt(stmt.Init); err != nil {
+			return err
+		}
+	}
+
+	var post func() error
+	if stmt.Post != nil {
+		post = func() error {
+			if err := g.genExpr(stmt.Post); err != nil {
+				return err
+			}
+			g.destroyTemps()
+			return nil
+		}
+	}
+	if err := g.genLoop(stmt.Label, g.condBranch(stmt.Cond), stmt.Body, post); err != nil {
+		return err
+	}
+	g.closeScope()
+	return nil
+}
+
+func (g *generator) genRangeStmt(stmt *syntax.RangeStmt) error {
+	g.openScope()
+	if err := g.genExpr(stmt.Low); err != nil {
+		return err
+	}
+	v := g.declareLocal(stmt.Var)
+	g.store(v.typ, asm.RAX, v.mem())
+
+	// The upper bound is only evaluated once.
+	if err := g.genExpr(stmt.High); err != nil {
+		return err
+	}
+	high := g.alloc(8)
+	g.emit(asm.Instr{Op: asm.MOV, Size: asm.S64, Src: asm.RAX, Dst: high})
+
+	check := func(end asm.Sym) error {
+		g.load(v.typ, v.mem(), asm.RAX)
+		g.emit(asm.Instr{Op: asm.MOV, Size: asm.S64, Src: high, Dst: asm.RCX})
+		g.emit(asm.Instr{Op: asm.CMP, Size: asm.S64, Src: asm.RCX, Dst: asm.RAX})
+		g.emit(asm.Instr{Op: asm.J, Cond: cond(lex.GEQ, isSigned(v.typ)), Dst: end})
+		return nil
+	}
+	// The variable can't overflow since it's less than the upper bound.
+	post := func() error {
+		g.load(v.typ, v.mem(), asm.RAX)
+		g.emit(asm.Instr{Op: asm.ADD, Size: asm.S64, Src: asm.Imm(1), Dst: asm.RAX})
+		g.store(v.typ, asm.RAX, v.mem())
+		return nil
+	}
+	if err := g.genLoop(stmt.Label, check, stmt.Body, post); err != nil {
+		return err
+	}
+	g.closeScope()
+	return nil
+}
+
+// condBranch returns a function that branches to the given label if cond
+// is false, or nil if there is no condition.
+func (g *generator) condBranch(cond syntax.Expr) func(end asm.Sym) error {
+	if cond == nil {
+		return nil
+	}
+	return func(end asm.Sym) error {
+		if err := g.genExpr(cond); err != nil {
+			return err
+		}
+		g.destroyTemps()
+		g.emit(asm.Instr{Op: asm.TEST, Size: asm.S64, Src: asm.RAX, Dst: asm.RAX})
+		g.emit(asm.Instr{Op: asm.J, Cond: asm.CondE, Dst: end})
+		return nil
+	}
+}
+
+// genLoop generates a loop. Before each iteration cond, if not nil, branches
+// to the end label to exit the loop, and after each iteration, including
+// on continue, post is generated if not nil.
+func (g *generator) genLoop(label *syntax.Ident, cond func(end asm.Sym) error, body *syntax.BlockStmt, post func() error) error {
+	start := g.newLabel()
+	next := g.newLabel()
+	end := g.newLabel()
+
+	var name string
+	if label != nil {
+		name = label.Name
+	}
+	g.loops = append(g.loops, loop{
+		label:         name,
+		continueLabel: next,
+		breakLabel:    end,
+		scopes:        len(g.scopes),
+	})
+	defer func() { g.loops = g.loops[:len(g.loops)-1] }()
+
+	g.emit(asm.Instr{Op: asm.LABEL, Dst: start})
+	if cond != nil {
+		if err := cond(end); err != nil {
+			return err
+		}
+	}
+
+	if err := g.genBlockStmt(body); err != nil {
+		return err
+	}
+	g.emit(asm.Instr{Op: asm.LABEL, Dst: next})
+	if post != nil {
+		if err := post(); err != nil {
+			return err
+		}
+	}
+	g.emit(asm.Instr{Op: asm.JMP, Dst: start})
+	g.emit(asm.Instr{Op: asm.LABEL, Dst: end})
+	return nil
+}
+
+// targetLoop returns the loop with the given label, or the innermost loop
+// if the label is nil.
+func (g *generator) targetLoop(label *syntax.Ident) (loop, bool) {
+	for i := len(g.loops) - 1; i >= 0; i-- {
+		if label == nil || g.loops[i].label == label.Name {
+			return g.loops[i], true
+		}
+	}
+	return loop{}, false
+}
+
+// Expressions.
+//
+// Each expression evaluates its result into RAX, extended to 64 bits
+// according to the type of the expression. Expressions of struct type
+// evaluate to the address of the struct instead.
+
+func (g *generator) genExpr(expr syntax.Expr) error {
+	// Constant expressions are evaluated by the type checker.
+	if v := g.info.ValueOf(expr); v != nil {
+		return g.genConst(expr, v)
+	}
+
+	switch expr := expr.(type) {
+	case *syntax.BasicLitExpr:
+		return g.genBasicLitExpr(expr)
+	case *syntax.VarExpr:
+		l := g.lookupLocal(expr.Name)
+		if isStruct(l.typ) {
+			g.addr(l, asm.RAX)
+			return nil
+		}
+		g.load(l.typ, l.mem(), asm.RAX)
+		return nil
+	case *syntax.AssignExpr:
+		return g.genAssignExpr(expr)
+	case *syntax.UnaryExpr:
+		return g.genUnaryExpr(expr)
+	case *syntax.BinaryExpr:
+		return g.genBinaryExpr(expr)
+	case *syntax.CallExpr:
+		return g.genCallExpr(expr)
+	case *syntax.CompositeLit:
+		return g.genCompositeLit(expr)
+	case *syntax.SelectorExpr:
+		return g.genSelectorExpr(expr)
+	default:
+		return fmt.Errorf("%s: unsupported expression", expr.Pos())
+	}
+}
+
+func (g *generator) genBasicLitExpr(expr *syntax.BasicLitExpr) error {
+	switch {
+	case expr.Kind == lex.NULL, expr.Kind == lex.BOOL && expr.Value == "false":
+		g.emit(asm.Instr{Op: asm.XOR, Size: asm.S32, Src: asm.RAX, Dst: asm.RAX})
+		return nil
+	case expr.Kind == lex.BOOL:
+		g.emit(asm.Instr{Op: asm.MOV, Size: asm.S32, Src: asm.Imm(1), Dst: asm.RAX})
+		return nil
+	}
+
+	return fmt.Errorf("%s: invalid integer literal: %s", expr.Pos(), expr.Value)
+}
+
+// genConst loads the value of the constant expression into RAX.
+func (g *generator) genConst(expr syntax.Expr, v *big.Int) error {
+	if !v.IsInt64() && !v.IsUint64() {
+		return fmt.Errorf("%s: constant %s overflows 64 bits", expr.Pos(), v)
+	}
+	// Values above the int64 range keep their bit pattern.
+	imm := v.Int64()
+	if !v.IsInt64() {
+		imm = int64(v.Uint64())
+	}
+	g.emit(asm.Instr{Op: asm.MOV, Size: asm.S64, Src: asm.Imm(imm), Dst: asm.RAX})
+	return nil
+}
+
+func (g *generator) genAssignExpr(expr *syntax.AssignExpr) error {
+	typ := g.info.TypeOf(expr.L)
+	if expr.Op != lex.ILLEGAL {
+		return g.genCompoundAssign(expr, typ)
+	}
+	if v, ok := expr.L.(*syntax.VarExpr); ok && !isStruct(typ) {
+		l := g.lookupLocal(v.Name)
+		if err := g.genExpr(expr.R); err != nil {
+			return err
+		}
+		g.store(l.typ, asm.RAX, l.mem())
+		return nil
+	}
+
+	// Evaluate the address of the target into RCX and the value into RAX.
+	if err := g.genAddr(expr.L); err != nil {
+		return err
+	}
+	g.push(asm.RAX)
+	if err := g.genExpr(expr.R); err != nil {
+		return err
+	}
+	g.move(expr.R)
+	g.pop(asm.RCX)
+
+	if types.NeedsDestroy(typ) {
+		// Destroy the previous value of the target.
+		g.push(asm.RAX)
+		g.push(asm.RCX)
+		if v, ok := expr.L.(*syntax.VarExpr); ok {
+			g.destroyLocal(g.lookupLocal(v.Name))
+		} else {
+			g.emit(asm.Instr{Op: asm.MOV, Size: asm.S64, Src: asm.RCX, Dst: asm.RAX})
+			g.destroy(typ)
+		}
+		g.pop(asm.RCX)
+		g.pop(asm.RAX)
+	}
+
+	if isStruct(typ) {
+		g.copyValue(typ, asm.Mem{Base: asm.RAX}, asm.Mem{Base: asm.RCX})
+		if v, ok := expr.L.(*syntax.VarExpr); ok {
+			g.setFlag(g.lookupLocal(v.Name), true)
+		}
+		g.emit(asm.Instr{Op: asm.MOV, Size: asm.S64, Src: asm.RCX, Dst: asm.RAX})
+		return nil
+	}
+	g.store(typ, asm.RAX, asm.Mem{Base: asm.RCX})
+	return nil
+}
+
+// genCompoundAssign generates an assignment such as 'x += y', leaving the
+// result in RAX.
+func (g *generator) genCompoundAssign(expr *syntax.AssignExpr, typ types.Type) error {
+	// Evaluate the address of the target, then the value into RCX.
+	if err := g.genAddr(expr.L); err != nil {
+		return err
+	}
+	g.push(asm.RAX)
+	if err := g.genExpr(expr.R); err != nil {
+		return err
+	}
+	g.emit(asm.Instr{Op: asm.MOV, Size: asm.S64, Src: asm.RAX, Dst: asm.RCX})
+
+	// Load the target into RAX, keeping its address on the stack.
+	g.pop(asm.RDX)
+	g.push(asm.RDX)
+	g.load(typ, asm.Mem{Base: asm.RDX}, asm.RAX)
+
+	if err := g.genArith(expr, expr.Op, typ); err != nil {
+		return err
+	}
+	g.pop(asm.RCX)
+	g.store(typ, asm.RAX, asm.Mem{Base: asm.RCX})
+	return nil
+}
+
+// genAddr evaluates the address of the addressable expression into RAX.
+func (g *generator) genAddr(expr syntax.Expr) error {
+	switch expr := expr.(type) {
+	case *syntax.VarExpr:
+		g.addr(g.lookupLocal(expr.Name), asm.RAX)
+		return nil
+	case *syntax.UnaryExpr:
+		if expr.Op != lex.MUL {
+			return fmt.Errorf("%s: unsupported assignment target", expr.Pos())
+		}
+		// The address is the value of the pointer.
+		return g.genExpr(expr.Expr)
+	case *syntax.SelectorExpr:
+		// The struct operand evaluates to its address, as does a pointer to
+		// a struct.
+		if err := g.genExpr(expr.X); err != nil {
+			return err
+		}
+		field := g.field(expr)
+		g.emit(asm.Instr{Op: asm.LEA, Size: asm.S64, Src: asm.Mem{Base: asm.RAX, Disp: int32(field.Offset)}, Dst: asm.RAX})
+		return nil
+	default:
+		return fmt.Errorf("%s: unsupported assignment target", expr.Pos())
+	}
+}
+
+func (g *generator) genCompositeLit(expr *syntax.CompositeLit) error {
+	typ := g.info.TypeOf(expr).(*types.Struct)
+
+	// Construct the struct in a temporary, with any omitted fields zeroed.
+	tmp := g.allocTemp(typ)
+	for off := 0; off < types.Sizeof(typ); off += 8 {
+		g.emit(asm.Instr{Op: asm.MOV, Size: asm.S64, Src: asm.Imm(0), Dst: asm.Mem{Base: asm.RBP, Disp: tmp.Disp + int32(off)}})
+	}
+	for _, fv := range expr.Fields {
+		if err := g.genExpr(fv.Value); err != nil {
+			return err
+		}
+		g.move(fv.Value)
+		field := typ.Field(fv.Name.Name)
+		dst := asm.Mem{Base: asm.RBP, Disp: tmp.Disp + int32(field.Offset)}
+		if isStruct(field.Type) {
+			g.copyValue(field.Type, asm.Mem{Base: asm.RAX}, dst)
+		} else {
+			g.store(field.Type, asm.RAX, dst)
+		}
+	}
+	g.emit(asm.Instr{Op: asm.LEA, Size: asm.S64, Src: tmp, Dst: asm.RAX})
+	g.registerTemp(expr, tmp, typ)
+	return nil
+}
+
+func (g *generator) genSelectorExpr(expr *syntax.SelectorExpr) error {
+	if err := g.genExpr(expr.X); err != nil {
+		return err
+	}
+	field := g.field(expr)
+	mem := asm.Mem{Base: asm.RAX, Disp: int32(field.Offset)}
+	if isStruct(field.Type) {
+		g.emit(asm.Instr{Op: asm.LEA, Size: asm.S64, Src: mem, Dst: asm.RAX})
+	} else {
+		g.load(field.Type, mem, asm.RAX)
+	}
+	return nil
+}
+
+// field returns the struct field selected by the expression.
+func (g *generator) field(expr *syntax.SelectorExpr) *types.Field {
+	typ := g.info.TypeOf(expr.X)
+	if p, ok := typ.(*types.Pointer); ok {
+		typ = p.Elem
+	}
+	s, ok := typ.(*types.Struct)
+	assert.Assertf(ok, "selector on non-struct: %s", expr.Sel.Name)
+	field := s.Field(expr.Sel.Name)
+	assert.Assertf(field != nil, "unknown field: %s", expr.Sel.Name)
+	return field
+}
+
+func (g *generator) genUnaryExpr(expr *syntax.UnaryExpr) error {
+	if expr.Op == lex.AND {
+		return g.genAddr(expr.Expr)
+	}
+
+	if err := g.genExpr(expr.Expr); err != nil {
+		return err
+	}
+
+	switch expr.Op {
+	case lex.MUL:
+		// A struct evaluates to its address, which is the pointer.
+		if typ := g.info.TypeOf(expr); !isStruct(typ) {
+			g.load(typ, asm.Mem{Base: asm.RAX}, asm.RAX)
+		}
+		return nil
+	case lex.SUB:
+		g.emit(asm.Instr{Op: asm.NEG, Size: asm.S64, Dst: asm.RAX})
+	case lex.TILDE:
+		g.emit(asm.Instr{Op: asm.NOT, Size: asm.S64, Dst: asm.RAX})
+	case lex.NOT:
+		// Booleans are always 0 or 1.
+		g.emit(asm.Instr{Op: asm.XOR, Size: asm.S64, Src: asm.Imm(1), Dst: asm.RAX})
+		return nil
+	default:
+		return fmt.Errorf("%s: unsupported unary operator: %s", expr.Pos(), expr.Op)
+	}
+
+	g.extend(g.info.TypeOf(expr.Expr), asm.RAX)
+	return nil
+}
+
+func (g *generator) genBinaryExpr(expr *syntax.BinaryExpr) error {
+	if expr.Op == lex.LAND || expr.Op == lex.LOR {
+		return g.genLogicalExpr(expr)
+	}
+
+	// Comparisons use the type of the operands rather than the result.
+	typ := g.info.TypeOf(expr.L)
+
+	// Evaluate the left operand into RAX and the right into RCX.
+	if err := g.genExpr(expr.L); err != nil {
+		return err
+	}
+	g.push(asm.RAX)
+	if err := g.genExpr(expr.R); err != nil {
+		return err
+	}
+	g.emit(asm.Instr{Op: asm.MOV, Size: asm.S64, Src: asm.RAX, Dst: asm.RCX})
+	g.pop(asm.RAX)
+
+	switch expr.Op {
+	case lex.EQL, lex.NEQ, lex.LSS, lex.LEQ, lex.GTR, lex.GEQ:
+		g.emit(asm.Instr{Op: asm.CMP, Size: asm.S64, Src: asm.RCX, Dst: asm.RAX})
+		g.emit(asm.Instr{Op: asm.SET, Cond: cond(expr.Op, isSigned(typ)), Dst: asm.RAX})
+		g.emit(asm.Instr{Op: asm.MOVZX, Size: asm.S8, Src: asm.RAX, Dst: asm.RAX})
+		return nil
+	default:
+		return g.genArith(expr, expr.Op, typ)
+	}
+}
+
+// genLogicalExpr generates '&&' or '||' with short-circuit evaluation, so
+// the right operand is only evaluated if the left operand doesn't decide
+// the result.
+//
+// Temporaries of the right operand are only destroyed if they were
+// created, since their drop flags are only set when evaluated.
+func (g *generator) genLogicalExpr(expr *syntax.BinaryExpr) error {
+	end := g.newLabel()
+	if err := g.genExpr(expr.L); err != nil {
+		return err
+	}
+	// Booleans are 0 or 1, so RAX already holds the result if the right
+	// operand is skipped.
+	cond := asm.CondE
+	if expr.Op == lex.LOR {
+		cond = asm.CondNE
+	}
+	g.emit(asm.Instr{Op: asm.TEST, Size: asm.S64, Src: asm.RAX, Dst: asm.RAX})
+	g.emit(asm.Instr{Op: asm.J, Cond: cond, Dst: end})
+	if err := g.genExpr(expr.R); err != nil {
+		return err
+	}
+	g.emit(asm.Instr{Op: asm.LABEL, Dst: end})
+	return nil
+}
+
+// genArith applies the arithmetic, bitwise or shift operator to RAX and
+// RCX, leaving the result in RAX extended according to the type.
+func (g *generator) genArith(node syntax.Node, op lex.Token, typ types.Type) error {
+	switch op {
+	case lex.ADD:
+		g.emit(asm.Instr{Op: asm.ADD, Size: asm.S64, Src: asm.RCX, Dst: asm.RAX})
+	case lex.SUB:
+		g.emit(asm.Instr{Op: asm.SUB, Size: asm.S64, Src: asm.RCX, Dst: asm.RAX})
+	case lex.MUL:
+		g.emit(asm.Instr{Op: asm.IMUL, Size: asm.S64, Src: asm.RCX, Dst: asm.RAX})
+	case lex.QUO, lex.REM:
+		if isSigned(typ) {
+			g.emit(asm.Instr{Op: asm.CQO})
+			g.emit(asm.Instr{Op: asm.IDIV, Size: asm.S64, Dst: asm.RCX})
+		} else {
+			g.emit(asm.Instr{Op: asm.XOR, Size: asm.S32, Src: asm.RDX, Dst: asm.RDX})
+			g.emit(asm.Instr{Op: asm.DIV, Size: asm.S64, Dst: asm.RCX})
+		}
+		if op == lex.REM {
+			g.emit(asm.Instr{Op: asm.MOV, Size: asm.S64, Src: asm.RDX, Dst: asm.RAX})
+		}
+	case lex.AND:
+		g.emit(asm.Instr{Op: asm.AND, Size: asm.S64, Src: asm.RCX, Dst: asm.RAX})
+	case lex.OR:
+		g.emit(asm.Instr{Op: asm.OR, Size: asm.S64, Src: asm.RCX, Dst: asm.RAX})
+	case lex.XOR:
+		g.emit(asm.Instr{Op: asm.XOR, Size: asm.S64, Src: asm.RCX, Dst: asm.RAX})
+	case lex.SHL, lex.SHR:
+		g.genShift(op, typ)
+	default:
+		return fmt.Errorf("%s: unsupported binary operator: %s", node.Pos(), op)
+	}
+
+	g.extend(typ, asm.RAX)
+	return nil
+}
+
+// genShift shifts RAX by the count in RCX. Right shifts of signed types are
+// arithmetic.
+//
+// x86 masks the count to 6 bits, so counts of 64 or more, including
+// negative counts, are handled separately to shift out all bits.
+func (g *generator) genShift(op lex.Token, typ types.Type) {
+	large := g.newLabel()
+	done := g.newLabel()
+	g.emit(asm.Instr{Op: asm.CMP, Size: asm.S64, Src: asm.Imm(63), Dst: asm.RCX})
+	g.emit(asm.Instr{Op: asm.J, Cond: asm.CondA, Dst: large})
+
+	shift := asm.SHL
+	if op == lex.SHR {
+		shift = asm.SHR
+		if isSigned(typ) {
+			shift = asm.SAR
+		}
+	}
+	g.emit(asm.Instr{Op: shift, Size: asm.S64, Src: asm.RCX, Dst: asm.RAX})
+	g.emit(asm.Instr{Op: asm.JMP, Dst: done})
+
+	g.emit(asm.Instr{Op: asm.LABEL, Dst: large})
+	if shift == asm.SAR {
+		// Fill with the sign bit.
+		g.emit(asm.Instr{Op: asm.SAR, Size: asm.S64, Src: asm.Imm(63), Dst: asm.RAX})
+	} else {
+		g.emit(asm.Instr{Op: asm.XOR, Size: asm.S32, Src: asm.RAX, Dst: asm.RAX})
+	}
+	g.emit(asm.Instr{Op: asm.LABEL, Dst: done})
+}
+
+func (g *generator) genCallExpr(expr *syntax.CallExpr) error {
+	obj := g.info.Uses[expr.Func]
+	assert.Assertf(obj != nil, "unresolved: %s", expr.Func.Name)
+	if obj.Kind == types.TypeName {
+		return g.genConversion(obj.Type, expr)
+	}
+
+	// A struct result is written to a temporary, whose address is passed
+	// as a hidden first argument.
+	regs := argRegs
+	var ret asm.Mem
+	if fn := obj.Type.(*types.Func); isStruct(fn.Return) {
+		ret = g.allocTemp(fn.Return)
+		regs = regs[1:]
+	}
+
+	// Evaluate the arguments in order into temporaries on the stack. A
+	// method receiver is passed by address as the first argument.
+	nArgs := len(expr.Args)
+	if expr.Recv != nil {
+		if err := g.genExpr(expr.Recv); err != nil {
+			return err
+		}
+		g.push(asm.RAX)
+		nArgs++
+	}
+	for _, arg := range expr.Args {
+		if err := g.genExpr(arg); err != nil {
+			return err
+		}
+		// Struct arguments are passed as the address of a copy, so the
+		// callee can't modify the caller's value. Arguments that need
+		// destroying are moved to the callee.
+		g.move(arg)
+		if typ := g.info.TypeOf(arg); isStruct(typ) {
+			tmp := g.allocTemp(typ)
+			g.copyValue(typ, asm.Mem{Base: asm.RAX}, tmp)
+			g.emit(asm.Instr{Op: asm.LEA, Size: asm.S64, Src: tmp, Dst: asm.RAX})
+		}
+		g.push(asm.RAX)
+	}
+	depth := g.depth
+
+	// The stack must be 16 byte aligned at the call, including any stack
+	// arguments.
+	nStack := max(nArgs-len(regs), 0)
+	if (g.depth+nStack)%2 != 0 {
+		g.emit(asm.Instr{Op: asm.SUB, Size: asm.S64, Src: asm.Imm(8), Dst: asm.RSP})
+		g.depth++
+	}
+
+	// argMem returns the address of the temporary containing argument i
+	// relative to the current stack pointer.
+	argMem := func(i int) asm.Mem {
+		return asm.Mem{
+			Base: asm.RSP,
+			Disp: int32(8 * (g.depth - depth + nArgs - 1 - i)),
+		}
+	}
+
+	// Push stack arguments in reverse order so the first is at the lowest
+	// address.
+	for i := nArgs - 1; i >= len(regs); i-- {
+		g.emit(asm.Instr{Op: asm.MOV, Size: asm.S64, Src: argMem(i), Dst: asm.RAX})
+		g.push(asm.RAX)
+	}
+	for i := 0; i < nArgs && i < len(regs); i++ {
+		g.emit(asm.Instr{Op: asm.MOV, Size: asm.S64, Src: argMem(i), Dst: regs[i]})
+	}
+	if len(regs) < len(argRegs) {
+		g.emit(asm.Instr{Op: asm.LEA, Size: asm.S64, Src: ret, Dst: argRegs[0]})
+	}
+
+	g.emit(asm.Instr{Op: asm.CALL, Dst: g.symbols[obj]})
+	if fn := obj.Type.(*types.Func); types.NeedsDestroy(fn.Return) {
+		g.registerTemp(expr, ret, fn.Return)
+	}
+
+	// Discard the temporaries, padding and stack arguments.
+	if n := g.depth - depth + nArgs; n > 0 {
+		g.emit(asm.Instr{Op: asm.ADD, Size: asm.S64, Src: asm.Imm(8 * n), Dst: asm.RSP})
+		g.depth -= n
+	}
+	return nil
+}
+
+func (g *generator) genConversion(typ types.Type, expr *syntax.CallExpr) error {
+	if len(expr.Args) != 1 {
+		return fmt.Errorf("%s: %s: conversion requires a single argument", expr.Pos(), expr.Func.Name)
+	}
+	if err := g.genExpr(expr.Args[0]); err != nil {
+		return err
+	}
+	if types.NeedsDestroy(typ) {
+		// The argument is moved into a new temporary.
+		g.move(expr.Args[0])
+		tmp := g.allocTemp(typ)
+		g.copyValue(typ, asm.Mem{Base: asm.RAX}, tmp)
+		g.emit(asm.Instr{Op: asm.LEA, Size: asm.S64, Src: tmp, Dst: asm.RAX})
+		g.registerTemp(expr, tmp, typ)
+	}
+	if !isStruct(typ) {
+		g.extend(typ, asm.RAX)
+	}
+	return nil
+}
+
+// Locals.
+
+type local struct {
+	offset int32
+	typ    types.Type
+	// indirect is set if the slot holds the address of the value rather
+	// than the value itself, such as for struct parameters.
+	indirect bool
+	// flag is the offset of the drop flag, which is set while the local
+	// holds a value that must be destroyed, or 0 if the type doesn't need
+	// destroying.
+	flag int32
+}
+
+// mem returns the stack address of the local.
+func (l *local) mem() asm.Mem {
+	return asm.Mem{Base: asm.RBP, Disp: l.offset}
+}
+
+// declareLocal allocates a stack slot for the variable defined by the
+// identifier.
+func (g *generator) declareLocal(name *syntax.Ident) *local {
+	obj, ok := g.info.Defs[name]
+	assert.Assertf(ok, "missing definition: %s", name.Name)
+
+	l := &local{
+		offset: g.allocTemp(obj.Type).Disp,
+		typ:    obj.Type,
+	}
+	g.locals[obj] = l
+	g.declareDestroy(l)
+	return l
+}
+
+// declareParam declares a parameter passed on the stack at the given offset
+// from the frame pointer.
+func (g *generator) declareParam(name *syntax.Ident, offset int32) {
+	obj, ok := g.info.Defs[name]
+	assert.Assertf(ok, "missing definition: %s", name.Name)
+
+	l := &local{
+		offset:   offset,
+		typ:      obj.Type,
+		indirect: isStruct(obj.Type),
+	}
+	g.locals[obj] = l
+	g.declareDestroy(l)
+}
+
+// lookupLocal returns the local variable the identifier refers to.
+func (g *generator) lookupLocal(name *syntax.Ident) *local {
+	obj := g.info.Uses[name]
+	assert.Assertf(obj != nil, "unresolved: %s", name.Name)
+	l, ok := g.locals[obj]
+	assert.Assertf(ok, "unknown local: %s", name.Name)
+	return l
+}
+
+// addr loads the address of the local into the register.
+func (g *generator) addr(l *local, reg asm.Reg) {
+	if l.indirect {
+		g.emit(asm.Instr{Op: asm.MOV, Size: asm.S64, Src: l.mem(), Dst: reg})
+	} else {
+		g.emit(asm.Instr{Op: asm.LEA, Size: asm.S64, Src: l.mem(), Dst: reg})
+	}
+}
+
+// alloc allocates a stack slot of at least size bytes in the frame,
+// aligned to 8 bytes.
+func (g *generator) alloc(size int) asm.Mem {
+	g.frameSize += int32((size + 7) &^ 7)
+	return asm.Mem{Base: asm.RBP, Disp: -g.frameSize}
+}
+
+// allocTemp allocates a stack slot for a value of the given type.
+func (g *generator) allocTemp(typ types.Type) asm.Mem {
+	return g.alloc(max(types.Sizeof(typ), 8))
+}
+
+// Helpers.
+
+func (g *generator) emit(instr asm.Instr) {
+	g.text = append(g.text, instr)
+}
+
+func (g *generator) push(reg asm.Reg) {
+	g.emit(asm.Instr{Op: asm.PUSH, Size: asm.S64, Dst: reg})
+	g.depth++
+}
+
+func (g *generator) pop(reg asm.Reg) {
+	g.emit(asm.Instr{Op: asm.POP, Size: asm.S64, Dst: reg})
+	g.depth--
+}
+
+func (g *generator) newLabel() asm.Sym {
+	g.labels++
+	return asm.Sym(fmt.Sprintf(".L%d", g.labels))
+}
+
+// load loads a value of the given type from memory into the register,
+// extending it to 64 bits.
+func (g *generator) load(typ types.Type, mem asm.Mem, reg asm.Reg) {
+	size := sizeOf(typ)
+	switch {
+	case size == asm.S64:
+		g.emit(asm.Instr{Op: asm.MOV, Size: asm.S64, Src: mem, Dst: reg})
+	case size == asm.S32 && !isSigned(typ):
+		// 32-bit moves implicitly zero the upper half of the register.
+		g.emit(asm.Instr{Op: asm.MOV, Size: asm.S32, Src: mem, Dst: reg})
+	case isSigned(typ):
+		g.emit(asm.Instr{Op: asm.MOVSX, Size: size, Src: mem, Dst: reg})
+	default:
+		g.emit(asm.Instr{Op: asm.MOVZX, Size: size, Src: mem, Dst: reg})
+	}
+}
+
+// store stores the register to memory, truncated to the given type.
+func (g *generator) store(typ types.Type, reg asm.Reg, mem asm.Mem) {
+	g.emit(asm.Instr{Op: asm.MOV, Size: sizeOf(typ), Src: reg, Dst: mem})
+}
+
+// extend truncates the register to the given type then extends it back to
+// 64 bits.
+func (g *generator) extend(typ types.Type, reg asm.Reg) {
+	size := sizeOf(typ)
+	switch {
+	case typ == types.Bool:
+		// Normalise booleans to 0 or 1.
+		g.emit(asm.Instr{Op: asm.TEST, Size: asm.S64, Src: reg, Dst: reg})
+		g.emit(asm.Instr{Op: asm.SET, Cond: asm.CondNE, Dst: reg})
+		g.emit(asm.Instr{Op: asm.MOVZX, Size: asm.S8, Src: reg, Dst: reg})
+	case size == asm.S64:
+	case size == asm.S32 && !isSigned(typ):
+		g.emit(asm.Instr{Op: asm.MOV, Size: asm.S32, Src: reg, Dst: reg})
+	case isSigned(typ):
+		g.emit(asm.Instr{Op: asm.MOVSX, Size: size, Src: reg, Dst: reg})
+	default:
+		g.emit(asm.Instr{Op: asm.MOVZX, Size: size, Src: reg, Dst: reg})
+	}
+}
+
+// copyValue copies a value of the given type from src to dst, using R11 as
+// a scratch register.
+func (g *generator) copyValue(typ types.Type, src, dst asm.Mem) {
+	size := types.Sizeof(typ)
+	for off := 0; off < size; {
+		var n asm.Size
+		switch {
+		case size-off >= 8:
+			n = asm.S64
+		case size-off >= 4:
+			n = asm.S32
+		case size-off >= 2:
+			n = asm.S16
+		default:
+			n = asm.S8
+		}
+		s := asm.Mem{Base: src.Base, Disp: src.Disp + int32(off)}
+		d := asm.Mem{Base: dst.Base, Disp: dst.Disp + int32(off)}
+		g.emit(asm.Instr{Op: asm.MOV, Size: n, Src: s, Dst: asm.R11})
+		g.emit(asm.Instr{Op: asm.MOV, Size: n, Src: asm.R11, Dst: d})
+		off += int(n)
+	}
+}
+
+func sizeOf(typ types.Type) asm.Size {
+	switch typ {
+	case types.Bool, types.U8, types.I8:
+		return asm.S8
+	case types.U16, types.I16:
+		return asm.S16
+	case types.U32, types.I32:
+		return asm.S32
+	default:
+		return asm.S64
+	}
+}
+
+func isStruct(typ types.Type) bool {
+	_, ok := typ.(*types.Struct)
+	return ok
+}
+
+func isSigned(typ types.Type) bool {
+	switch typ {
+	case types.I8, types.I16, types.I32, types.I64:
+		return true
+	case nil:
+		// Untyped constants default to signed.
+		return true
+	default:
+		return false
+	}
+}
+
+// cond returns the condition code for the comparison operator.
+func cond(op lex.Token, signed bool) asm.Cond {
+	switch op {
+	case lex.EQL:
+		return asm.CondE
+	case lex.NEQ:
+		return asm.CondNE
+	case lex.LSS:
+		if signed {
+			return asm.CondL
+		}
+		return asm.CondB
+	case lex.LEQ:
+		if signed {
+			return asm.CondLE
+		}
+		return asm.CondBE
+	case lex.GTR:
+		if signed {
+			return asm.CondG
+		}
+		return asm.CondA
+	case lex.GEQ:
+		if signed {
+			return asm.CondGE
+		}
+		return asm.CondAE
+	default:
+		assert.Panicf("unsupported comparison: %s", op)
+		return 0 // Unreachable.
+	}
+}
@@ -0,0 +1,161 @@
+package codegen
+
+import (
+	"github.com/andydunstall/nova/pkg/asm"
+	"github.com/andydunstall/nova/pkg/syntax"
+	"github.com/andydunstall/nova/pkg/types"
+)
+
+// Destructors.
+//
+// Values whose type needs destroying (see [types.NeedsDestroy]) are
+// destroyed when their scope exits, including when jumping out of the scope
+// with return, break or continue. Locals are destroyed in reverse
+// declaration order, and temporaries at the end of the statement that
+// created them.
+//
+// Since values may be moved on only some paths, each such local and
+// temporary has a drop flag, which is set while it holds a value. Moving
+// the value clears the flag so it is only destroyed once.
+
+func (g *generator) openScope() {
+	g.scopes = append(g.scopes, nil)
+}
+
+// closeScope destroys the locals of the innermost scope and closes it.
+func (g *generator) closeScope() {
+	g.destroyScopes(len(g.scopes) - 1)
+	g.scopes = g.scopes[:len(g.scopes)-1]
+}
+
+// destroyScopes destroys the locals of the scopes from the innermost scope
+// to scope n, preserving RAX.
+func (g *generator) destroyScopes(n int) {
+	var locals []*local
+	for i := len(g.scopes) - 1; i >= n; i-- {
+		for j := len(g.scopes[i]) - 1; j >= 0; j-- {
+			locals = append(locals, g.scopes[i][j])
+		}
+	}
+	g.destroyLocals(locals)
+}
+
+// destroyTemps destroys the temporaries of the current statement,
+// preserving RAX.
+func (g *generator) destroyTemps() {
+	var temps []*local
+	for i := len(g.temps) - 1; i >= 0; i-- {
+		temps = append(temps, g.temps[i])
+	}
+	g.destroyLocals(temps)
+	g.temps = nil
+}
+
+// destroyLocals destroys each local in order, preserving RAX.
+func (g *generator) destroyLocals(locals []*local) {
+	if len(locals) == 0 {
+		return
+	}
+	g.push(asm.RAX)
+	for _, l := range locals {
+		g.destroyLocal(l)
+	}
+	g.pop(asm.RAX)
+}
+
+// declareDestroy adds a drop flag to the local if its type needs
+// destroying, and adds it to the current scope.
+func (g *generator) declareDestroy(l *local) {
+	if !types.NeedsDestroy(l.typ) {
+		return
+	}
+	l.flag = g.alloc(8).Disp
+	g.flags = append(g.flags, l.flag)
+	g.scopes[len(g.scopes)-1] = append(g.scopes[len(g.scopes)-1], l)
+}
+
+// registerTemp adds a temporary holding the value of the expression, which
+// is destroyed at the end of the statement unless it's moved.
+func (g *generator) registerTemp(expr syntax.Expr, mem asm.Mem, typ types.Type) {
+	l := &local{
+		offset: mem.Disp,
+		typ:    typ,
+		flag:   g.alloc(8).Disp,
+	}
+	g.flags = append(g.flags, l.flag)
+	g.setFlag(l, true)
+	g.temps = append(g.temps, l)
+	g.tempOf[expr] = l
+}
+
+// move clears the drop flag of the variable or temporary holding the
+// value of the expression, if its type needs destroying.
+func (g *generator) move(expr syntax.Expr) {
+	if !types.NeedsDestroy(g.info.TypeOf(expr)) {
+		return
+	}
+	if v, ok := expr.(*syntax.VarExpr); ok {
+		g.setFlag(g.lookupLocal(v.Name), false)
+	} else if l, ok := g.tempOf[expr]; ok {
+		g.setFlag(l, false)
+	}
+}
+
+// setFlag sets or clears the drop flag of the local, if it has one.
+func (g *generator) setFlag(l *local, set bool) {
+	if l.flag == 0 {
+		return
+	}
+	var v asm.Imm
+	if set {
+		v = 1
+	}
+	g.emit(asm.Instr{Op: asm.MOV, Size: asm.S8, Src: v, Dst: asm.Mem{Base: asm.RBP, Disp: l.flag}})
+}
+
+// destroyLocal destroys the value of the local if its drop flag is set,
+// and clears the flag.
+func (g *generator) destroyLocal(l *local) {
+	skip := g.newLabel()
+	flag := asm.Mem{Base: asm.RBP, Disp: l.flag}
+	g.emit(asm.Instr{Op: asm.CMP, Size: asm.S8, Src: asm.Imm(0), Dst: flag})
+	g.emit(asm.Instr{Op: asm.J, Cond: asm.CondE, Dst: skip})
+	g.setFlag(l, false)
+	g.addr(l, asm.RAX)
+	g.destroy(l.typ)
+	g.emit(asm.Instr{Op: asm.LABEL, Dst: skip})
+}
+
+// destroy destroys the value of the given type whose address is in RAX, by
+// calling its destructor then destroying its fields in reverse order.
+func (g *generator) destroy(typ types.Type) {
+	s := typ.(*types.Struct)
+	top := asm.Mem{Base: asm.RSP}
+
+	g.push(asm.RAX)
+	if d := s.Destructor(); d != nil {
+		g.emit(asm.Instr{Op: asm.MOV, Size: asm.S64, Src: top, Dst: asm.RDI})
+		g.call(g.symbols[d])
+	}
+	for i := len(s.Fields) - 1; i >= 0; i-- {
+		f := s.Fields[i]
+		if !types.NeedsDestroy(f.Type) {
+			continue
+		}
+		g.emit(asm.Instr{Op: asm.MOV, Size: asm.S64, Src: top, Dst: asm.RAX})
+		g.emit(asm.Instr{Op: asm.LEA, Size: asm.S64, Src: asm.Mem{Base: asm.RAX, Disp: int32(f.Offset)}, Dst: asm.RAX})
+		g.destroy(f.Type)
+	}
+	g.pop(asm.RAX)
+}
+
+// call calls the function, aligning the stack to 16 bytes.
+func (g *generator) call(sym asm.Sym) {
+	if g.depth%2 != 0 {
+		g.emit(asm.Instr{Op: asm.SUB, Size: asm.S64, Src: asm.Imm(8), Dst: asm.RSP})
+		g.emit(asm.Instr{Op: asm.CALL, Dst: sym})
+		g.emit(asm.Instr{Op: asm.ADD, Size: asm.S64, Src: asm.Imm(8), Dst: asm.RSP})
+		return
+	}
+	g.emit(asm.Instr{Op: asm.CALL, Dst: sym})
+}
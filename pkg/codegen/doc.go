@@ -0,0 +1,3 @@
+// Package codegen generates x86-64 Linux assembly from a type checked Nova
+// AST.
+package codegen
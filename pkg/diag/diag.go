@@ -0,0 +1,183 @@
+package diag
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/andydunstall/nova/pkg/lex"
+)
+
+// Severity is the severity of a diagnostic.
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+	Note
+)
+
+var severityStrs = [...]string{
+	Error:   "error",
+	Warning: "warning",
+	Note:    "note",
+}
+
+func (s Severity) String() string {
+	return severityStrs[s]
+}
+
+// Span is a range in a source file.
+type Span struct {
+	Start lex.Position
+	End   lex.Position
+}
+
+// Label annotates a span with a message.
+type Label struct {
+	Span    Span
+	Message string
+}
+
+// Diagnostic is a message about the source, such as an error or warning.
+type Diagnostic struct {
+	Severity Severity
+	Message  string
+
+	// Span is the primary source span the diagnostic refers to.
+	Span Span
+	// Label is an optional message shown with the primary span.
+	Label string
+
+	// Labels are secondary spans related to the diagnostic, such as the
+	// previous declaration of a redeclared name.
+	Labels []Label
+
+	// Notes contains additional information shown after the source.
+	Notes []string
+
+	// Expected describes what the parser expected for a syntax error at
+	// an unexpected token, such as "';'" or "expression", and Found
+	// describes the token found instead. Both are empty for other
+	// diagnostics.
+	Expected string
+	Found    string
+}
+
+// Errorf returns an error diagnostic at the given span.
+func Errorf(span Span, format string, a ...any) *Diagnostic {
+	return &Diagnostic{
+		Severity: Error,
+		Message:  fmt.Sprintf(format, a...),
+		Span:     span,
+	}
+}
+
+// Warningf returns a warning diagnostic at the given span.
+func Warningf(span Span, format string, a ...any) *Diagnostic {
+	return &Diagnostic{
+		Severity: Warning,
+		Message:  fmt.Sprintf(format, a...),
+		Span:     span,
+	}
+}
+
+// WithLabel sets the label of the primary span.
+func (d *Diagnostic) WithLabel(label string) *Diagnostic {
+	d.Label = label
+	return d
+}
+
+// WithSecondary adds a secondary label.
+func (d *Diagnostic) WithSecondary(span Span, message string) *Diagnostic {
+	d.Labels = append(d.Labels, Label{
+		Span:    span,
+		Message: message,
+	})
+	return d
+}
+
+// WithExpected records the expected and found tokens of a syntax error.
+func (d *Diagnostic) WithExpected(expected, found string) *Diagnostic {
+	d.Expected = expected
+	d.Found = found
+	return d
+}
+
+// WithNote adds a note.
+func (d *Diagnostic) WithNote(format string, a ...any) *Diagnostic {
+	d.Notes = append(d.Notes, fmt.Sprintf(format, a...))
+	return d
+}
+
+// Error formats the diagnostic on a single line, as 'file:line:column:
+// message'. Warnings and notes include their severity.
+func (d *Diagnostic) Error() string {
+	if d.Severity == Error {
+		return fmt.Sprintf("%s: %s", d.Span.Start, d.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", d.Span.Start, d.Severity, d.Message)
+}
+
+// List is a list of diagnostics.
+type List []*Diagnostic
+
+// Add adds the diagnostic to the list.
+func (l *List) Add(d *Diagnostic) {
+	*l = append(*l, d)
+}
+
+// HasErrors returns whether the list contains any errors.
+func (l List) HasErrors() bool {
+	for _, d := range l {
+		if d.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+// Sort sorts the diagnostics by position.
+func (l List) Sort() {
+	sort.SliceStable(l, func(i, j int) bool {
+		a, b := l[i].Span.Start, l[j].Span.Start
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Offset < b.Offset
+	})
+}
+
+func (l List) Error() string {
+	var b strings.Builder
+	for i, d := range l {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(d.Error())
+	}
+	return b.String()
+}
+
+// Err returns the list as an error if it contains any errors, otherwise
+// nil.
+func (l List) Err() error {
+	if !l.HasErrors() {
+		return nil
+	}
+	return l
+}
+
+// Node is a syntax node with a source range.
+type Node interface {
+	Pos() lex.Position
+	End() lex.Position
+}
+
+// SpanOf returns the span of the node.
+func SpanOf(n Node) Span {
+	return Span{
+		Start: n.Pos(),
+		End:   n.End(),
+	}
+}
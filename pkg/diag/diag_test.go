@@ -0,0 +1,220 @@
+package diag_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/andydunstall/nova/pkg/diag"
+	"github.com/andydunstall/nova/pkg/lex"
+)
+
+const testSrc = `fn main() -> i32 {
+	let x: i32 = 1;
+	return x + y;
+}
+`
+
+// span returns the span on the given line of testSrc, between the 1-based
+// start and end columns.
+func span(line, start, end int) diag.Span {
+	return diag.Span{
+		Start: lex.Position{Filename: "test.nv", Line: line, Column: start},
+		End:   lex.Position{Filename: "test.nv", Line: line, Column: end},
+	}
+}
+
+func TestFprint(t *testing.T) {
+	tests := []struct {
+		name string
+		d    *diag.Diagnostic
+		want string
+	}{
+		{
+			"label",
+			diag.Errorf(span(3, 13, 14), "undefined: y").
+				WithLabel("not found in this scope"),
+			`error: undefined: y
+ --> test.nv:3:13
+  |
+3 |     return x + y;
+  |                ^ not found in this scope
+
+`,
+		},
+		{
+			"warning with note",
+			diag.Warningf(span(2, 6, 7), "unused variable: x").
+				WithNote("remove the declaration"),
+			`warning: unused variable: x
+ --> test.nv:2:6
+  |
+2 |     let x: i32 = 1;
+  |         ^
+  |
+  = note: remove the declaration
+
+`,
+		},
+		{
+			"secondary on another line",
+			diag.Errorf(span(3, 9, 14), "mismatched types").
+				WithLabel("expected i32").
+				WithSecondary(span(1, 14, 17), "return type declared here"),
+			`error: mismatched types
+ --> test.nv:3:9
+  |
+1 | fn main() -> i32 {
+  |              --- return type declared here
+...
+3 |     return x + y;
+  |            ^^^^^ expected i32
+
+`,
+		},
+		{
+			// The secondary label on the primary's line shares its marker
+			// line, ordered by column.
+			"secondary on the same line",
+			diag.Errorf(span(3, 13, 14), "undefined: y").
+				WithLabel("not found in this scope").
+				WithSecondary(span(3, 9, 10), "left operand"),
+			`error: undefined: y
+ --> test.nv:3:13
+  |
+3 |     return x + y;
+  |            -   ^ not found in this scope
+  |            left operand
+
+`,
+		},
+		{
+			"no position",
+			diag.Errorf(diag.Span{Start: lex.Position{Filename: "test.nv"}}, "missing main function").
+				WithNote("declare main"),
+			`error: missing main function
+  = note: declare main
+
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			sources := map[string][]byte{"test.nv": []byte(testSrc)}
+			if err := diag.Fprint(&b, diag.List{tt.d}, sources); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestFprint_MissingSource(t *testing.T) {
+	var b bytes.Buffer
+	d := diag.Errorf(span(3, 13, 14), "undefined: y").WithLabel("not found")
+	if err := diag.Fprint(&b, diag.List{d}, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := "error: undefined: y\n --> test.nv:3:13\n\n"
+	if b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+}
+
+func TestFprintJSON(t *testing.T) {
+	list := diag.List{
+		diag.Errorf(span(3, 13, 14), "expected ';', found 'y'").
+			WithLabel("expected ';'").
+			WithExpected("';'", "'y'").
+			WithSecondary(span(1, 1, 3), "in this function").
+			WithNote("a note"),
+		diag.Warningf(span(2, 6, 7), "unused"),
+	}
+
+	var b bytes.Buffer
+	if err := diag.FprintJSON(&b, list); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []map[string]any
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatalf("invalid json: %s", err)
+	}
+	want := []map[string]any{
+		{
+			"severity": "error",
+			"message":  "expected ';', found 'y'",
+			"span":     jsonSpan(3, 13, 14),
+			"label":    "expected ';'",
+			"labels": []any{
+				map[string]any{
+					"span":    jsonSpan(1, 1, 3),
+					"message": "in this function",
+				},
+			},
+			"notes":    []any{"a note"},
+			"expected": "';'",
+			"found":    "'y'",
+		},
+		{
+			"severity": "warning",
+			"message":  "unused",
+			"span":     jsonSpan(2, 6, 7),
+		},
+	}
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(want)
+	if !bytes.Equal(gotJSON, wantJSON) {
+		t.Errorf("got %s, want %s", gotJSON, wantJSON)
+	}
+}
+
+func TestFprintJSON_Empty(t *testing.T) {
+	var b bytes.Buffer
+	if err := diag.FprintJSON(&b, nil); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "[]\n" {
+		t.Errorf("got %q, want []", b.String())
+	}
+}
+
+func jsonSpan(line, start, end int) map[string]any {
+	return map[string]any{
+		"file": "test.nv",
+		"start": map[string]any{
+			"offset": 0.0, "line": float64(line), "column": float64(start),
+		},
+		"end": map[string]any{
+			"offset": 0.0, "line": float64(line), "column": float64(end),
+		},
+	}
+}
+
+func TestList(t *testing.T) {
+	at := func(file string, offset int) diag.Span {
+		pos := lex.Position{Filename: file, Offset: offset, Line: 1, Column: offset + 1}
+		return diag.Span{Start: pos, End: pos}
+	}
+	list := diag.List{
+		diag.Warningf(at("b.nv", 1), "w"),
+		diag.Errorf(at("a.nv", 5), "second"),
+		diag.Errorf(at("a.nv", 2), "first"),
+	}
+	list.Sort()
+	want := "a.nv:1:3: first\na.nv:1:6: second\nb.nv:1:2: warning: w"
+	if list.Error() != want {
+		t.Errorf("got %q, want %q", list.Error(), want)
+	}
+	if list.Err() == nil {
+		t.Error("expected error")
+	}
+
+	warnings := diag.List{diag.Warningf(at("a.nv", 0), "w")}
+	if warnings.HasErrors() || warnings.Err() != nil {
+		t.Error("warnings aren't errors")
+	}
+}
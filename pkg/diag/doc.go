@@ -0,0 +1,3 @@
+// Package diag manages compiler diagnostics, such as syntax and type errors,
+// and rendering them for users and tools.
+package diag
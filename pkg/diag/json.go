@@ -0,0 +1,80 @@
+package diag
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/andydunstall/nova/pkg/lex"
+)
+
+type jsonPosition struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type jsonSpan struct {
+	File  string       `json:"file"`
+	Start jsonPosition `json:"start"`
+	End   jsonPosition `json:"end"`
+}
+
+type jsonLabel struct {
+	Span    jsonSpan `json:"span"`
+	Message string   `json:"message"`
+}
+
+type jsonDiagnostic struct {
+	Severity string      `json:"severity"`
+	Message  string      `json:"message"`
+	Span     jsonSpan    `json:"span"`
+	Label    string      `json:"label,omitempty"`
+	Labels   []jsonLabel `json:"labels,omitempty"`
+	Notes    []string    `json:"notes,omitempty"`
+	Expected string      `json:"expected,omitempty"`
+	Found    string      `json:"found,omitempty"`
+}
+
+// FprintJSON writes the diagnostics as a JSON array, for editors and CI
+// tooling.
+func FprintJSON(w io.Writer, list List) error {
+	out := make([]jsonDiagnostic, 0, len(list))
+	for _, d := range list {
+		jd := jsonDiagnostic{
+			Severity: d.Severity.String(),
+			Message:  d.Message,
+			Span:     toJSONSpan(d.Span),
+			Label:    d.Label,
+			Notes:    d.Notes,
+			Expected: d.Expected,
+			Found:    d.Found,
+		}
+		for _, label := range d.Labels {
+			jd.Labels = append(jd.Labels, jsonLabel{
+				Span:    toJSONSpan(label.Span),
+				Message: label.Message,
+			})
+		}
+		out = append(out, jd)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func toJSONSpan(span Span) jsonSpan {
+	return jsonSpan{
+		File:  span.Start.Filename,
+		Start: toJSONPosition(span.Start),
+		End:   toJSONPosition(span.End),
+	}
+}
+
+func toJSONPosition(pos lex.Position) jsonPosition {
+	return jsonPosition{
+		Offset: pos.Offset,
+		Line:   pos.Line,
+		Column: pos.Column,
+	}
+}
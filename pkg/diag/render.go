@@ -0,0 +1,193 @@
+package diag
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// tabWidth is the number of columns a tab is expanded to in snippets.
+const tabWidth = 4
+
+// Fprint renders the diagnostics in a human readable format, including a
+// snippet of the source lines referenced by each span, such as:
+//
+//	error: undefined: y
+//	 --> examples/loops.nv:4:7
+//	  |
+//	4 |         x = y + 1;
+//	  |             ^ not found in this scope
+//
+// sources maps file names to their contents. If a file is missing its
+// snippets are omitted.
+func Fprint(w io.Writer, list List, sources map[string][]byte) error {
+	bw := bufio.NewWriter(w)
+	lines := make(map[string][][]byte)
+	for _, d := range list {
+		src, ok := sources[d.Span.Start.Filename]
+		if ok {
+			if _, ok := lines[d.Span.Start.Filename]; !ok {
+				lines[d.Span.Start.Filename] = bytes.Split(src, []byte("\n"))
+			}
+		}
+		render(bw, d, lines[d.Span.Start.Filename])
+	}
+	return bw.Flush()
+}
+
+// annotation is a span to underline in a snippet.
+type annotation struct {
+	span    Span
+	marker  byte
+	message string
+}
+
+func render(w io.Writer, d *Diagnostic, lines [][]byte) {
+	fmt.Fprintf(w, "%s: %s\n", d.Severity, d.Message)
+
+	annotations := []annotation{{span: d.Span, marker: '^', message: d.Label}}
+	for _, label := range d.Labels {
+		// Only include labels in the same file, since only that file's
+		// source is available.
+		if label.Span.Start.Filename != d.Span.Start.Filename {
+			continue
+		}
+		annotations = append(annotations, annotation{span: label.Span, marker: '-', message: label.Message})
+	}
+	sort.SliceStable(annotations, func(i, j int) bool {
+		a, b := annotations[i].span.Start, annotations[j].span.Start
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+
+	maxLine := 0
+	for _, a := range annotations {
+		maxLine = max(maxLine, a.span.Start.Line)
+	}
+	gutter := strings.Repeat(" ", len(strconv.Itoa(maxLine)))
+
+	if d.Span.Start.IsValid() {
+		fmt.Fprintf(w, "%s--> %s\n", gutter, d.Span.Start)
+	}
+
+	if lines != nil && d.Span.Start.IsValid() {
+		fmt.Fprintf(w, "%s |\n", gutter)
+		prevLine := 0
+		for i := 0; i < len(annotations); {
+			// Group the annotations on the same line so they share a
+			// single marker line.
+			line := annotations[i].span.Start.Line
+			j := i + 1
+			for j < len(annotations) && annotations[j].span.Start.Line == line {
+				j++
+			}
+			group := annotations[i:j]
+			i = j
+
+			if !group[0].span.Start.IsValid() || line > len(lines) {
+				continue
+			}
+			if prevLine != 0 && line > prevLine+1 {
+				fmt.Fprintln(w, "...")
+			}
+			text := expandTabs(lines[line-1])
+			fmt.Fprintf(w, "%*d | %s\n", len(gutter), line, strings.TrimRight(text, " \r"))
+			prevLine = line
+
+			renderMarkers(w, gutter, lines[line-1], group)
+		}
+	}
+
+	if len(d.Notes) > 0 {
+		if lines != nil && d.Span.Start.IsValid() {
+			fmt.Fprintf(w, "%s |\n", gutter)
+		}
+		for _, note := range d.Notes {
+			fmt.Fprintf(w, "%s = note: %s\n", gutter, note)
+		}
+	}
+
+	fmt.Fprintln(w)
+}
+
+// renderMarkers writes a single marker line underlining each annotation
+// on the source line. The rightmost message follows the markers, and the
+// other messages are written on their own lines below their markers.
+func renderMarkers(w io.Writer, gutter string, line []byte, group []annotation) {
+	var marks []byte
+	draw := func(a annotation) {
+		start, end := underline(line, a.span)
+		for len(marks) < end {
+			marks = append(marks, ' ')
+		}
+		for i := start; i < end; i++ {
+			marks[i] = a.marker
+		}
+	}
+	// Draw the primary annotation last so it takes precedence where spans
+	// overlap.
+	for _, a := range group {
+		if a.marker != '^' {
+			draw(a)
+		}
+	}
+	for _, a := range group {
+		if a.marker == '^' {
+			draw(a)
+		}
+	}
+
+	last := group[len(group)-1]
+	out := string(marks)
+	if last.message != "" {
+		out += " " + last.message
+	}
+	fmt.Fprintf(w, "%s | %s\n", gutter, out)
+
+	for _, a := range group[:len(group)-1] {
+		if a.message == "" {
+			continue
+		}
+		start, _ := underline(line, a.span)
+		fmt.Fprintf(w, "%s | %s%s\n", gutter, strings.Repeat(" ", start), a.message)
+	}
+}
+
+// underline returns the visual column range to underline for the span on
+// the given line. Spans ending on a later line are underlined to the end
+// of the line, and empty spans have a single marker.
+func underline(line []byte, span Span) (int, int) {
+	start := visualColumn(line, span.Start.Column)
+	end := visualColumn(line, len(line)+1)
+	if span.End.IsValid() && span.End.Line == span.Start.Line {
+		end = visualColumn(line, span.End.Column)
+	}
+	if end <= start {
+		end = start + 1
+	}
+	return start, end
+}
+
+// visualColumn returns the 0-based column of the 1-based byte column once
+// tabs are expanded.
+func visualColumn(line []byte, column int) int {
+	col := 0
+	for i := 0; i < column-1; i++ {
+		if i < len(line) && line[i] == '\t' {
+			col += tabWidth
+		} else {
+			col++
+		}
+	}
+	return col
+}
+
+func expandTabs(line []byte) string {
+	return strings.ReplaceAll(string(line), "\t", strings.Repeat(" ", tabWidth))
+}
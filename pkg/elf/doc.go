@@ -0,0 +1,2 @@
+// Package elf writes x86-64 ELF64 object files and static executables.
+package elf
@@ -0,0 +1,301 @@
+package elf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// BaseAddr is the virtual address executables are loaded at.
+const BaseAddr = 0x400000
+
+// Symbol is a function defined at an offset into the text section.
+type Symbol struct {
+	Name   string
+	Offset uint64
+	Global bool
+}
+
+// File describes the contents of an ELF file.
+type File struct {
+	// Text contains the machine code of the .text section.
+	Text []byte
+
+	// Symbols contains the symbols written to the symbol table.
+	Symbols []Symbol
+
+	// Entry is the name of the entrypoint symbol. Only used by executables.
+	Entry string
+}
+
+const (
+	ehdrSize = 64
+	phdrSize = 56
+	shdrSize = 64
+	symSize  = 24
+
+	etRel  = 1
+	etExec = 2
+
+	emX86_64 = 62
+
+	ptLoad = 1
+	pfX    = 1
+	pfR    = 4
+
+	shtProgbits = 1
+	shtSymtab   = 2
+	shtStrtab   = 3
+
+	shfAlloc     = 0x2
+	shfExecinstr = 0x4
+
+	stbLocal  = 0
+	stbGlobal = 1
+	sttFunc   = 2
+
+	// Section indices.
+	shText     = 1
+	shSymtab   = 2
+	shStrtab   = 3
+	shShstrtab = 4
+	shNum      = 5
+)
+
+// WriteExecutable writes a static executable that loads the text section
+// at [BaseAddr] and starts execution at the entry symbol.
+func WriteExecutable(w io.Writer, f *File) error {
+	return write(w, f, true)
+}
+
+// WriteObject writes a relocatable object file containing the text section
+// and symbols.
+func WriteObject(w io.Writer, f *File) error {
+	return write(w, f, false)
+}
+
+func write(w io.Writer, f *File, exec bool) error {
+	// Layout the file. The executable maps the whole file, including
+	// headers, so text offsets map directly to virtual addresses.
+	offset := uint64(ehdrSize)
+	if exec {
+		offset += phdrSize
+	}
+	textOff := align(offset, 16)
+	textEnd := textOff + uint64(len(f.Text))
+
+	var textAddr uint64
+	if exec {
+		textAddr = BaseAddr + textOff
+	}
+
+	// Symbol table. Local symbols must come before global symbols.
+	strtab := newStrtab()
+	symtab := new(bytes.Buffer)
+	// Null symbol.
+	symtab.Write(make([]byte, symSize))
+	nLocal := 1
+
+	var entry uint64
+	var foundEntry bool
+	for _, global := range []bool{false, true} {
+		for _, sym := range f.Symbols {
+			if sym.Global != global {
+				continue
+			}
+			// Skip assembler local labels.
+			if strings.HasPrefix(sym.Name, ".L") {
+				continue
+			}
+
+			if sym.Offset > uint64(len(f.Text)) {
+				return fmt.Errorf("symbol out of range: %s", sym.Name)
+			}
+			if sym.Name == f.Entry {
+				entry = textAddr + sym.Offset
+				foundEntry = true
+			}
+
+			bind := byte(stbLocal)
+			if global {
+				bind = stbGlobal
+			} else {
+				nLocal++
+			}
+			writeSym(symtab, strtab.add(sym.Name), bind<<4|sttFunc, shText, textAddr+sym.Offset)
+		}
+	}
+	if exec && !foundEntry {
+		return fmt.Errorf("missing entry symbol: %s", f.Entry)
+	}
+
+	shstrtab := newStrtab()
+	textName := shstrtab.add(".text")
+	symtabName := shstrtab.add(".symtab")
+	strtabName := shstrtab.add(".strtab")
+	shstrtabName := shstrtab.add(".shstrtab")
+
+	symtabOff := align(textEnd, 8)
+	strtabOff := symtabOff + uint64(symtab.Len())
+	shstrtabOff := strtabOff + uint64(strtab.buf.Len())
+	shOff := align(shstrtabOff+uint64(shstrtab.buf.Len()), 8)
+
+	buf := new(bytes.Buffer)
+
+	// ELF header.
+	buf.Write([]byte{0x7f, 'E', 'L', 'F', 2 /* 64-bit */, 1 /* little endian */, 1 /* version */, 0 /* System V ABI */})
+	buf.Write(make([]byte, 8))
+	typ := uint16(etRel)
+	var phOff uint64
+	var phNum uint16
+	if exec {
+		typ = etExec
+		phOff = ehdrSize
+		phNum = 1
+	}
+	writeLE(buf, typ)
+	writeLE(buf, uint16(emX86_64))
+	writeLE(buf, uint32(1))
+	writeLE(buf, entry)
+	writeLE(buf, phOff)
+	writeLE(buf, shOff)
+	writeLE(buf, uint32(0))
+	writeLE(buf, uint16(ehdrSize))
+	writeLE(buf, uint16(phdrSize))
+	writeLE(buf, phNum)
+	writeLE(buf, uint16(shdrSize))
+	writeLE(buf, uint16(shNum))
+	writeLE(buf, uint16(shShstrtab))
+
+	// Program header.
+	if exec {
+		writeLE(buf, uint32(ptLoad))
+		writeLE(buf, uint32(pfR|pfX))
+		writeLE(buf, uint64(0))
+		writeLE(buf, uint64(BaseAddr))
+		writeLE(buf, uint64(BaseAddr))
+		writeLE(buf, textEnd)
+		writeLE(buf, textEnd)
+		writeLE(buf, uint64(0x1000))
+	}
+
+	pad(buf, textOff)
+	buf.Write(f.Text)
+	pad(buf, symtabOff)
+	buf.Write(symtab.Bytes())
+	buf.Write(strtab.buf.Bytes())
+	buf.Write(shstrtab.buf.Bytes())
+	pad(buf, shOff)
+
+	// Section headers.
+	buf.Write(make([]byte, shdrSize))
+	writeShdr(buf, shdr{
+		name:      textName,
+		typ:       shtProgbits,
+		flags:     shfAlloc | shfExecinstr,
+		addr:      textAddr,
+		offset:    textOff,
+		size:      uint64(len(f.Text)),
+		addralign: 16,
+	})
+	writeShdr(buf, shdr{
+		name:      symtabName,
+		typ:       shtSymtab,
+		offset:    symtabOff,
+		size:      uint64(symtab.Len()),
+		link:      shStrtab,
+		info:      uint32(nLocal),
+		addralign: 8,
+		entsize:   symSize,
+	})
+	writeShdr(buf, shdr{
+		name:      strtabName,
+		typ:       shtStrtab,
+		offset:    strtabOff,
+		size:      uint64(strtab.buf.Len()),
+		addralign: 1,
+	})
+	writeShdr(buf, shdr{
+		name:      shstrtabName,
+		typ:       shtStrtab,
+		offset:    shstrtabOff,
+		size:      uint64(shstrtab.buf.Len()),
+		addralign: 1,
+	})
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+type shdr struct {
+	name      uint32
+	typ       uint32
+	flags     uint64
+	addr      uint64
+	offset    uint64
+	size      uint64
+	link      uint32
+	info      uint32
+	addralign uint64
+	entsize   uint64
+}
+
+func writeShdr(buf *bytes.Buffer, s shdr) {
+	writeLE(buf, s.name)
+	writeLE(buf, s.typ)
+	writeLE(buf, s.flags)
+	writeLE(buf, s.addr)
+	writeLE(buf, s.offset)
+	writeLE(buf, s.size)
+	writeLE(buf, s.link)
+	writeLE(buf, s.info)
+	writeLE(buf, s.addralign)
+	writeLE(buf, s.entsize)
+}
+
+func writeSym(buf *bytes.Buffer, name uint32, info byte, shndx uint16, value uint64) {
+	writeLE(buf, name)
+	buf.WriteByte(info)
+	buf.WriteByte(0) // Other.
+	writeLE(buf, shndx)
+	writeLE(buf, value)
+	writeLE(buf, uint64(0)) // Size.
+}
+
+// strtab is an ELF string table.
+type strtab struct {
+	buf *bytes.Buffer
+}
+
+func newStrtab() *strtab {
+	// String tables start with an empty string.
+	buf := new(bytes.Buffer)
+	buf.WriteByte(0)
+	return &strtab{buf: buf}
+}
+
+// add adds the string to the table and returns its offset.
+func (t *strtab) add(s string) uint32 {
+	off := uint32(t.buf.Len())
+	t.buf.WriteString(s)
+	t.buf.WriteByte(0)
+	return off
+}
+
+func writeLE(buf *bytes.Buffer, v any) {
+	// Writing to a bytes.Buffer never fails.
+	_ = binary.Write(buf, binary.LittleEndian, v)
+}
+
+// pad pads the buffer with zeros up to the given offset.
+func pad(buf *bytes.Buffer, offset uint64) {
+	for uint64(buf.Len()) < offset {
+		buf.WriteByte(0)
+	}
+}
+
+func align(v uint64, n uint64) uint64 {
+	return (v + n - 1) &^ (n - 1)
+}
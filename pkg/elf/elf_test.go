@@ -0,0 +1,195 @@
+package elf_test
+
+import (
+	"bytes"
+	stdelf "debug/elf"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/andydunstall/nova/pkg/elf"
+)
+
+// testFile calls f, which returns 42, then exits with its result:
+//
+//	_start:
+//		call f
+//		movl %eax, %edi
+//		movl $60, %eax
+//		syscall
+//	f:
+//		movl $42, %eax
+//		ret
+//	.L1:
+var testFile = &elf.File{
+	Text: []byte{
+		0xe8, 0x09, 0x00, 0x00, 0x00,
+		0x89, 0xc7,
+		0xb8, 0x3c, 0x00, 0x00, 0x00,
+		0x0f, 0x05,
+		0xb8, 0x2a, 0x00, 0x00, 0x00,
+		0xc3,
+	},
+	Symbols: []elf.Symbol{
+		{Name: "_start", Offset: 0, Global: true},
+		{Name: "f", Offset: 14},
+		{Name: ".L1", Offset: 20},
+	},
+	Entry: "_start",
+}
+
+func TestWriteExecutable_Run(t *testing.T) {
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		t.Skip("executables only run on linux/amd64")
+	}
+
+	path := filepath.Join(t.TempDir(), "out")
+	var buf bytes.Buffer
+	if err := elf.WriteExecutable(&buf, testFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	err := exec.Command(path).Run()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("run: got err %v, want exit code 42", err)
+	}
+	if got := exitErr.ExitCode(); got != 42 {
+		t.Errorf("got exit code %d, want 42", got)
+	}
+}
+
+func TestWriteExecutable_Headers(t *testing.T) {
+	var buf bytes.Buffer
+	if err := elf.WriteExecutable(&buf, testFile); err != nil {
+		t.Fatal(err)
+	}
+	f, err := stdelf.NewFile(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.Class != stdelf.ELFCLASS64 || f.Data != stdelf.ELFDATA2LSB {
+		t.Errorf("got class %s data %s", f.Class, f.Data)
+	}
+	if f.Type != stdelf.ET_EXEC || f.Machine != stdelf.EM_X86_64 {
+		t.Errorf("got type %s machine %s", f.Type, f.Machine)
+	}
+
+	text := f.Section(".text")
+	if text == nil {
+		t.Fatal("missing .text section")
+	}
+	data, err := text.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, testFile.Text) {
+		t.Errorf("got text %x, want %x", data, testFile.Text)
+	}
+	if text.Addr < elf.BaseAddr || f.Entry != text.Addr {
+		t.Errorf("got entry %#x, want start of text %#x", f.Entry, text.Addr)
+	}
+
+	if len(f.Progs) != 1 {
+		t.Fatalf("got %d program headers, want 1", len(f.Progs))
+	}
+	prog := f.Progs[0]
+	if prog.Type != stdelf.PT_LOAD || prog.Flags != stdelf.PF_R|stdelf.PF_X || prog.Vaddr != elf.BaseAddr {
+		t.Errorf("got program header %+v", prog.ProgHeader)
+	}
+	if prog.Off+prog.Filesz < text.Offset+text.Size {
+		t.Errorf("text isn't loaded")
+	}
+
+	checkSymbols(t, f, text.Addr)
+}
+
+func TestWriteObject(t *testing.T) {
+	var buf bytes.Buffer
+	if err := elf.WriteObject(&buf, testFile); err != nil {
+		t.Fatal(err)
+	}
+	f, err := stdelf.NewFile(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.Type != stdelf.ET_REL || len(f.Progs) != 0 {
+		t.Errorf("got type %s with %d program headers", f.Type, len(f.Progs))
+	}
+	data, err := f.Section(".text").Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, testFile.Text) {
+		t.Errorf("got text %x, want %x", data, testFile.Text)
+	}
+
+	checkSymbols(t, f, 0)
+}
+
+func TestWriteExecutable_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		file *elf.File
+	}{
+		{"missing entry", &elf.File{
+			Text:  []byte{0xc3},
+			Entry: "_start",
+		}},
+		{"symbol out of range", &elf.File{
+			Text: []byte{0xc3},
+			Symbols: []elf.Symbol{
+				{Name: "_start", Offset: 2, Global: true},
+			},
+			Entry: "_start",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := elf.WriteExecutable(&bytes.Buffer{}, tt.file); err == nil {
+				t.Error("got nil error")
+			}
+		})
+	}
+}
+
+// checkSymbols checks the symbol table contains the local symbols then the
+// global symbols of testFile, excluding assembler local labels.
+func checkSymbols(t *testing.T, f *stdelf.File, textAddr uint64) {
+	t.Helper()
+
+	syms, err := f.Symbols()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		name string
+		addr uint64
+		bind stdelf.SymBind
+	}{
+		{"f", textAddr + 14, stdelf.STB_LOCAL},
+		{"_start", textAddr, stdelf.STB_GLOBAL},
+	}
+	if len(syms) != len(want) {
+		t.Fatalf("got %d symbols, want %d", len(syms), len(want))
+	}
+	for i, sym := range syms {
+		if sym.Name != want[i].name || sym.Value != want[i].addr || stdelf.ST_BIND(sym.Info) != want[i].bind {
+			t.Errorf("got symbol %s at %#x (%s), want %s at %#x (%s)",
+				sym.Name, sym.Value, stdelf.ST_BIND(sym.Info),
+				want[i].name, want[i].addr, want[i].bind,
+			)
+		}
+		if stdelf.ST_TYPE(sym.Info) != stdelf.STT_FUNC || sym.Section != 1 {
+			t.Errorf("%s: got type %s in section %d", sym.Name, stdelf.ST_TYPE(sym.Info), sym.Section)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package lex
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// IntValue returns the value of an [INT] literal, such as '1_000', '0xff',
+// '0b1010' or '0o17'.
+func IntValue(lit string) (*big.Int, error) {
+	base := 10
+	digits := lit
+	if len(lit) > 2 && lit[0] == '0' {
+		switch lower(lit[1]) {
+		case 'x':
+			base, digits = 16, lit[2:]
+		case 'b':
+			base, digits = 2, lit[2:]
+		case 'o':
+			base, digits = 8, lit[2:]
+		}
+	}
+
+	v, ok := new(big.Int).SetString(strings.ReplaceAll(digits, "_", ""), base)
+	if !ok {
+		return nil, fmt.Errorf("invalid integer literal: %s", lit)
+	}
+	return v, nil
+}
@@ -1,6 +1,31 @@
 package lex
 
+import "fmt"
+
+// Position describes a location in a source file.
 type Position struct {
-	Line   int
-	Column int
+	Filename string
+	Offset   int // Byte offset, starting at 0.
+	Line     int // Line number, starting at 1.
+	Column   int // Column number, starting at 1 (byte count).
+}
+
+// IsValid reports whether the position has been set.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns the position formatted as 'file:line:column', omitting the
+// file name if unknown.
+func (p Position) String() string {
+	if !p.IsValid() {
+		if p.Filename != "" {
+			return p.Filename
+		}
+		return "-"
+	}
+	if p.Filename == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
 }
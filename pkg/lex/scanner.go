@@ -1,6 +1,9 @@
 package lex
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 const (
 	eof = 0xff // end of file
@@ -18,7 +21,9 @@ type Scanner struct {
 	pos Position
 }
 
-func NewScanner(src []byte) *Scanner {
+// NewScanner returns a scanner for the given source. The filename is only
+// used to annotate positions.
+func NewScanner(filename string, src []byte) *Scanner {
 	ch := byte(eof)
 	if len(src) > 0 {
 		ch = src[0]
@@ -28,12 +33,16 @@ func NewScanner(src []byte) *Scanner {
 		ch:     ch,
 		offset: 0,
 		pos: Position{
-			Line:   1,
-			Column: 1,
+			Filename: filename,
+			Offset:   0,
+			Line:     1,
+			Column:   1,
 		},
 	}
 }
 
+// Scan returns the next token, its literal value (for identifiers and
+// literals) and the position of its first character.
 func (s *Scanner) Scan() (tok Token, lit string, pos Position, err error) {
 	s.skipWhitespace()
 
@@ -44,7 +53,7 @@ func (s *Scanner) Scan() (tok Token, lit string, pos Position, err error) {
 		lit = s.scanIdentifier()
 		tok = Lookup(lit)
 	case isDecimal(ch):
-		lit = s.scanNumber()
+		lit, err = s.scanNumber()
 		tok = INT
 	default:
 		s.next()
@@ -97,6 +106,9 @@ func (s *Scanner) Scan() (tok Token, lit string, pos Position, err error) {
 			if s.ch == '&' {
 				tok = LAND
 				s.next()
+			} else if s.ch == '=' {
+				tok = AND_ASSIGN
+				s.next()
 			} else {
 				tok = AND
 			}
@@ -104,11 +116,19 @@ func (s *Scanner) Scan() (tok Token, lit string, pos Position, err error) {
 			if s.ch == '|' {
 				tok = LOR
 				s.next()
+			} else if s.ch == '=' {
+				tok = OR_ASSIGN
+				s.next()
 			} else {
 				tok = OR
 			}
 		case '^':
-			tok = XOR
+			if s.ch == '=' {
+				tok = XOR_ASSIGN
+				s.next()
+			} else {
+				tok = XOR
+			}
 		case '=':
 			if s.ch == '=' {
 				tok = EQL
@@ -127,6 +147,10 @@ func (s *Scanner) Scan() (tok Token, lit string, pos Position, err error) {
 			if s.ch == '<' {
 				tok = SHL
 				s.next()
+				if s.ch == '=' {
+					tok = SHL_ASSIGN
+					s.next()
+				}
 			} else if s.ch == '=' {
 				tok = LEQ
 				s.next()
@@ -137,6 +161,10 @@ func (s *Scanner) Scan() (tok Token, lit string, pos Position, err error) {
 			if s.ch == '>' {
 				tok = SHR
 				s.next()
+				if s.ch == '=' {
+					tok = SHR_ASSIGN
+					s.next()
+				}
 			} else if s.ch == '=' {
 				tok = GEQ
 				s.next()
@@ -152,53 +180,97 @@ func (s *Scanner) Scan() (tok Token, lit string, pos Position, err error) {
 		case '}':
 			tok = RBRACE
 		case ':':
-			tok = COLON
+			if s.ch == ':' {
+				tok = COLONCOLON
+				s.next()
+			} else {
+				tok = COLON
+			}
 		case ';':
 			tok = SEMICOLON
 		case ',':
 			tok = COMMA
+		case '.':
+			if s.ch == '.' {
+				tok = DOTDOT
+				s.next()
+			} else {
+				tok = PERIOD
+			}
 		case '~':
 			tok = TILDE
 		case eof:
 			tok = EOF
 		default:
-			err = fmt.Errorf("unexpected character: %s", ch)
+			tok = ILLEGAL
+			lit = string(ch)
+			err = fmt.Errorf("unexpected character: %q", ch)
 		}
 	}
 
 	return
 }
 
-func (s *Scanner) scanIdentifier() string {
-	for i, b := range s.src[s.offset:] {
-		if 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || b == '_' || '0' <= b && b <= '9' {
-			continue
-		}
+// Pos returns the position immediately after the last scanned token.
+func (s *Scanner) Pos() Position {
+	return s.pos
+}
 
-		ident := s.src[s.offset : s.offset+i]
-		s.offset += i
-		s.ch = s.src[s.offset]
-		return string(ident)
+func (s *Scanner) scanIdentifier() string {
+	offset := s.offset
+	for isLetter(s.ch) || isDecimal(s.ch) {
+		s.next()
 	}
-
-	// Will never get here as we know there is one character to scan.
-	panic("eof")
+	return string(s.src[offset:s.offset])
 }
 
-func (s *Scanner) scanNumber() string {
-	for i, b := range s.src[s.offset:] {
-		if '0' <= b && b <= '9' {
-			continue
+// scanNumber scans an integer literal, which may have a '0x', '0b' or '0o'
+// prefix and '_' separators between digits.
+//
+// A malformed literal is still returned as a single token, along with an
+// error describing the problem.
+func (s *Scanner) scanNumber() (string, error) {
+	offset := s.offset
+
+	base := 10
+	if s.ch == '0' {
+		s.next()
+		switch lower(s.ch) {
+		case 'x':
+			base = 16
+			s.next()
+		case 'b':
+			base = 2
+			s.next()
+		case 'o':
+			base = 8
+			s.next()
 		}
+	}
+	digitsOffset := s.offset
 
-		ident := s.src[s.offset : s.offset+i]
-		s.offset += i
-		s.ch = s.src[s.offset]
-		return string(ident)
+	// Scan all following letters and digits so an invalid digit doesn't
+	// split the literal into multiple tokens.
+	var err error
+	for isLetter(s.ch) || isDecimal(s.ch) {
+		if s.ch != '_' && digitVal(s.ch) >= base && err == nil {
+			err = fmt.Errorf("invalid digit %q in %s literal", s.ch, baseName(base))
+		}
+		s.next()
 	}
 
-	// Will never get here as we know there is one character to scan.
-	panic("eof")
+	lit := string(s.src[offset:s.offset])
+	digits := string(s.src[digitsOffset:s.offset])
+	if err != nil {
+		return lit, err
+	}
+	if base != 10 && strings.Trim(digits, "_") == "" {
+		return lit, fmt.Errorf("%s literal has no digits", baseName(base))
+	}
+	if strings.Contains(digits, "__") || strings.HasSuffix(digits, "_") {
+		return lit, fmt.Errorf("'_' must separate successive digits")
+	}
+	return lit, nil
 }
 
 func (s *Scanner) skipWhitespace() {
@@ -208,7 +280,7 @@ func (s *Scanner) skipWhitespace() {
 			s.next()
 		} else if s.offset < len(s.src)-1 && s.ch == '/' && s.src[s.offset+1] == '/' {
 			// Comment. Skip to next line.
-			for s.ch != '\n' {
+			for s.ch != '\n' && s.ch != eof {
 				s.next()
 			}
 		} else {
@@ -223,7 +295,9 @@ func (s *Scanner) next() {
 		s.pos.Column = 0
 	}
 
-	s.pos.Column++
+	if s.offset < len(s.src) {
+		s.pos.Column++
+	}
 	if s.offset < len(s.src)-1 {
 		s.offset++
 		s.ch = s.src[s.offset]
@@ -231,6 +305,7 @@ func (s *Scanner) next() {
 		s.offset = len(s.src)
 		s.ch = eof
 	}
+	s.pos.Offset = s.offset
 }
 
 func isLetter(ch byte) bool {
@@ -239,4 +314,30 @@ func isLetter(ch byte) bool {
 
 func isDecimal(ch byte) bool { return '0' <= ch && ch <= '9' }
 
+// digitVal returns the value of the digit, or 16 if ch isn't a digit in
+// any supported base.
+func digitVal(ch byte) int {
+	switch {
+	case isDecimal(ch):
+		return int(ch - '0')
+	case 'a' <= lower(ch) && lower(ch) <= 'f':
+		return int(lower(ch)-'a') + 10
+	default:
+		return 16
+	}
+}
+
+func baseName(base int) string {
+	switch base {
+	case 2:
+		return "binary"
+	case 8:
+		return "octal"
+	case 16:
+		return "hexadecimal"
+	default:
+		return "decimal"
+	}
+}
+
 func lower(ch byte) byte { return ('a' - 'A') | ch }
@@ -0,0 +1,196 @@
+package lex_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/andydunstall/nova/pkg/lex"
+)
+
+func TestScanner_Positions(t *testing.T) {
+	src := "fn main() {\n\treturn a+10; // c\n}\n"
+	tests := []struct {
+		tok    lex.Token
+		lit    string
+		offset int
+		line   int
+		col    int
+	}{
+		{lex.FN, "fn", 0, 1, 1},
+		{lex.IDENT, "main", 3, 1, 4},
+		{lex.LPAREN, "", 7, 1, 8},
+		{lex.RPAREN, "", 8, 1, 9},
+		{lex.LBRACE, "", 10, 1, 11},
+		{lex.RETURN, "return", 13, 2, 2},
+		{lex.IDENT, "a", 20, 2, 9},
+		{lex.ADD, "", 21, 2, 10},
+		{lex.INT, "10", 22, 2, 11},
+		{lex.SEMICOLON, "", 24, 2, 13},
+		{lex.RBRACE, "", 31, 3, 1},
+		{lex.EOF, "", 33, 4, 1},
+	}
+
+	s := lex.NewScanner("test.nv", []byte(src))
+	for _, tt := range tests {
+		tok, lit, pos, err := s.Scan()
+		if err != nil {
+			t.Fatalf("scan: %s", err)
+		}
+		if tok != tt.tok || lit != tt.lit {
+			t.Fatalf("got %s %q, want %s %q", tok, lit, tt.tok, tt.lit)
+		}
+		want := lex.Position{
+			Filename: "test.nv",
+			Offset:   tt.offset,
+			Line:     tt.line,
+			Column:   tt.col,
+		}
+		if pos != want {
+			t.Errorf("%s: got position %+v, want %+v", tok, pos, want)
+		}
+	}
+}
+
+func TestPosition_String(t *testing.T) {
+	tests := []struct {
+		pos  lex.Position
+		want string
+	}{
+		{lex.Position{Filename: "a.nv", Offset: 5, Line: 2, Column: 3}, "a.nv:2:3"},
+		{lex.Position{Line: 2, Column: 3}, "2:3"},
+		{lex.Position{Filename: "a.nv"}, "a.nv"},
+		{lex.Position{}, "-"},
+	}
+	for _, tt := range tests {
+		if got := tt.pos.String(); got != tt.want {
+			t.Errorf("%+v: got %q, want %q", tt.pos, got, tt.want)
+		}
+	}
+}
+
+func TestScanner_Number(t *testing.T) {
+	tests := []struct {
+		src string
+		lit string
+		err string
+	}{
+		{"0", "0", ""},
+		{"123", "123", ""},
+		{"09", "09", ""},
+		{"1_000_000", "1_000_000", ""},
+		{"0xff", "0xff", ""},
+		{"0XFF", "0XFF", ""},
+		{"0xdead_BEEF", "0xdead_BEEF", ""},
+		{"0x_ff", "0x_ff", ""},
+		{"0b1010", "0b1010", ""},
+		{"0B1_0", "0B1_0", ""},
+		{"0o17", "0o17", ""},
+		{"0O7_7", "0O7_7", ""},
+		{"12;", "12", ""},
+		{"1+2", "1", ""},
+
+		{"0b102", "0b102", "invalid digit '2' in binary literal"},
+		{"0o18", "0o18", "invalid digit '8' in octal literal"},
+		{"0xfg", "0xfg", "invalid digit 'g' in hexadecimal literal"},
+		{"12ab", "12ab", "invalid digit 'a' in decimal literal"},
+		{"0x", "0x", "hexadecimal literal has no digits"},
+		{"0b_", "0b_", "binary literal has no digits"},
+		{"0o", "0o", "octal literal has no digits"},
+		{"1__0", "1__0", "'_' must separate successive digits"},
+		{"1_", "1_", "'_' must separate successive digits"},
+		{"0x1_", "0x1_", "'_' must separate successive digits"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			s := lex.NewScanner("test.nv", []byte(tt.src))
+			tok, lit, pos, err := s.Scan()
+			if tok != lex.INT {
+				t.Fatalf("got token %s, want INT", tok)
+			}
+			if lit != tt.lit {
+				t.Errorf("got literal %q, want %q", lit, tt.lit)
+			}
+			if pos.Offset != 0 || pos.Line != 1 || pos.Column != 1 {
+				t.Errorf("got position %s, want 1:1", pos)
+			}
+			if tt.err == "" && err != nil {
+				t.Errorf("got err %q, want nil", err)
+			}
+			if tt.err != "" && (err == nil || err.Error() != tt.err) {
+				t.Errorf("got err %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestScanner_Ident(t *testing.T) {
+	tests := []struct {
+		src string
+		tok lex.Token
+	}{
+		{"x", lex.IDENT},
+		{"true", lex.BOOL},
+		{"false", lex.BOOL},
+		{"truth", lex.IDENT},
+		{"False", lex.IDENT},
+		{"fn", lex.FN},
+		{"null", lex.NULL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			s := lex.NewScanner("test.nv", []byte(tt.src))
+			tok, lit, _, err := s.Scan()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tok != tt.tok {
+				t.Errorf("got token %s, want %s", tok, tt.tok)
+			}
+			if lit != tt.src {
+				t.Errorf("got literal %q, want %q", lit, tt.src)
+			}
+		})
+	}
+}
+
+func TestIntValue(t *testing.T) {
+	tests := []struct {
+		lit  string
+		want string
+	}{
+		{"0", "0"},
+		{"42", "42"},
+		{"09", "9"},
+		{"1_000_000", "1000000"},
+		{"0xff", "255"},
+		{"0XfF", "255"},
+		{"0x_ff", "255"},
+		{"0b1010", "10"},
+		{"0B1_0", "2"},
+		{"0o17", "15"},
+		{"0O7_7", "63"},
+		{"0xffffffffffffffff", "18446744073709551615"},
+		{"18446744073709551616", "18446744073709551616"},
+		{"0x1_0000_0000_0000_0000_0000", "1208925819614629174706176"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.lit, func(t *testing.T) {
+			v, err := lex.IntValue(tt.lit)
+			if err != nil {
+				t.Fatalf("%s: %s", tt.lit, err)
+			}
+			want, _ := new(big.Int).SetString(tt.want, 10)
+			if v.Cmp(want) != 0 {
+				t.Errorf("%s: got %s, want %s", tt.lit, v, want)
+			}
+		})
+	}
+}
+
+func TestIntValue_Invalid(t *testing.T) {
+	for _, lit := range []string{"0x", "0b2", "12ab", ""} {
+		if v, err := lex.IntValue(lit); err == nil {
+			t.Errorf("%s: got %s, want error", lit, v)
+		}
+	}
+}
@@ -8,7 +8,8 @@ import (
 type Token int
 
 const (
-	EOF Token = iota
+	ILLEGAL Token = iota
+	EOF
 
 	// Identifiers and literals.
 	literal_beg
@@ -30,6 +31,11 @@ const (
 	MUL_ASSIGN // *=
 	QUO_ASSIGN // /=
 	REM_ASSIGN // %=
+	AND_ASSIGN // &=
+	OR_ASSIGN  // |=
+	XOR_ASSIGN // ^=
+	SHL_ASSIGN // <<=
+	SHR_ASSIGN // >>=
 
 	AND // &
 	OR  // |
@@ -52,21 +58,26 @@ const (
 	LEQ    // <=
 	GEQ    // >=
 
-	LPAREN    // (
-	LBRACE    // {
-	RPAREN    // )
-	RBRACE    // }
-	COLON     // :
-	SEMICOLON // ;
-	COMMA     // ,
-	ARROW     // ->
-	TILDE     // ~
+	LPAREN     // (
+	LBRACE     // {
+	RPAREN     // )
+	RBRACE     // }
+	COLON      // :
+	COLONCOLON // ::
+	SEMICOLON  // ;
+	COMMA      // ,
+	PERIOD     // .
+	DOTDOT     // ..
+	ARROW      // ->
+	TILDE      // ~
 	operator_end
 
 	// Keywords.
 	keyword_beg
 	FN
 	RETURN
+	STRUCT
+	NULL
 
 	LET
 	MUT
@@ -75,13 +86,16 @@ const (
 	ELSE
 
 	LOOP
+	FOR
+	IN
 	CONTINUE
 	BREAK
 	keyword_end
 )
 
 var tokens = [...]string{
-	EOF: "EOF",
+	ILLEGAL: "ILLEGAL",
+	EOF:     "EOF",
 
 	IDENT: "IDENT",
 	INT:   "INT",
@@ -98,9 +112,14 @@ var tokens = [...]string{
 	MUL_ASSIGN: "*=",
 	QUO_ASSIGN: "/=",
 	REM_ASSIGN: "%=",
+	AND_ASSIGN: "&=",
+	OR_ASSIGN:  "|=",
+	XOR_ASSIGN: "^=",
+	SHL_ASSIGN: "<<=",
+	SHR_ASSIGN: ">>=",
 
 	AND: "&",
-	OR:  "^",
+	OR:  "|",
 	XOR: "^",
 	SHL: "<<",
 	SHR: ">>",
@@ -120,18 +139,23 @@ var tokens = [...]string{
 	LEQ:    "<=",
 	GEQ:    ">=",
 
-	LPAREN:    "(",
-	LBRACE:    "{",
-	RPAREN:    ")",
-	RBRACE:    "}",
-	COLON:     ":",
-	SEMICOLON: ";",
-	COMMA:     ",",
-	ARROW:     "->",
-	TILDE:     "-~",
+	LPAREN:     "(",
+	LBRACE:     "{",
+	RPAREN:     ")",
+	RBRACE:     "}",
+	COLON:      ":",
+	COLONCOLON: "::",
+	SEMICOLON:  ";",
+	COMMA:      ",",
+	PERIOD:     ".",
+	DOTDOT:     "..",
+	ARROW:      "->",
+	TILDE:      "~",
 
 	FN:     "fn",
 	RETURN: "return",
+	STRUCT: "struct",
+	NULL:   "null",
 
 	LET: "let",
 	MUT: "mut",
@@ -140,6 +164,8 @@ var tokens = [...]string{
 	ELSE: "else",
 
 	LOOP:     "loop",
+	FOR:      "for",
+	IN:       "in",
 	CONTINUE: "continue",
 	BREAK:    "break",
 }
@@ -161,15 +187,53 @@ func init() {
 	}
 }
 
-// Lookup maps an identifier to its keyword token or [IDENT] (if not a
-// keyword).
+// Lookup maps an identifier to its keyword token, [BOOL] for the boolean
+// literals true and false, or [IDENT] (if neither).
 func Lookup(ident string) Token {
 	if tok, is_keyword := keywords[ident]; is_keyword {
 		return tok
 	}
+	if ident == "true" || ident == "false" {
+		return BOOL
+	}
 	return IDENT
 }
 
+// IsAssign returns whether the token is an assignment, including compound
+// assignments such as [ADD_ASSIGN].
+func (tok Token) IsAssign() bool {
+	return tok == ASSIGN || tok.AssignOp() != ILLEGAL
+}
+
+// AssignOp returns the binary operator of a compound assignment, such as
+// [ADD] for [ADD_ASSIGN], or [ILLEGAL] if tok isn't a compound assignment.
+func (tok Token) AssignOp() Token {
+	switch tok {
+	case ADD_ASSIGN:
+		return ADD
+	case SUB_ASSIGN:
+		return SUB
+	case MUL_ASSIGN:
+		return MUL
+	case QUO_ASSIGN:
+		return QUO
+	case REM_ASSIGN:
+		return REM
+	case AND_ASSIGN:
+		return AND
+	case OR_ASSIGN:
+		return OR
+	case XOR_ASSIGN:
+		return XOR
+	case SHL_ASSIGN:
+		return SHL
+	case SHR_ASSIGN:
+		return SHR
+	default:
+		return ILLEGAL
+	}
+}
+
 func (tok Token) IsLiteral() bool {
 	return literal_beg < tok && tok < literal_end
 }
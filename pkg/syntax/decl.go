@@ -1,28 +1,69 @@
 package syntax
 
 type Decl interface {
+	Node
 	decl()
 }
 
 type VarDecl struct {
+	Span
+
+	Mut  bool
 	Name *Ident
 	Expr Expr
-	Type string
+	// Type is nil if omitted, in which case it's inferred from Expr.
+	Type Type
 }
 
 func (n *VarDecl) decl() {}
 
 type FuncParam struct {
+	Span
+
+	Mut  bool
 	Name *Ident
-	Type string
+	Type Type
+}
+
+// SelfParam declares the receiver of a method, such as 'mut self'.
+type SelfParam struct {
+	Span
+
+	Mut bool
 }
 
 type FuncDecl struct {
+	Span
+
+	// Recv is the type the function is a member of, such as 'MyStruct' in
+	// 'fn MyStruct::sum()', or nil if the function isn't a member.
+	Recv *Ident
 	Name *Ident
 	Body *BlockStmt
 
-	Params     []FuncParam
-	ReturnType string
+	// Self is the explicit receiver parameter of a method, or nil if the
+	// receiver is implicit.
+	Self *SelfParam
+
+	Params []FuncParam
+	// ReturnType is nil if the function doesn't return a value.
+	ReturnType Type
 }
 
 func (n *FuncDecl) decl() {}
+
+type StructDecl struct {
+	Span
+
+	Name   *Ident
+	Fields []*Field
+}
+
+func (n *StructDecl) decl() {}
+
+type Field struct {
+	Span
+
+	Name *Ident
+	Type Type
+}
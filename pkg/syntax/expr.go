@@ -3,17 +3,24 @@ package syntax
 import "github.com/andydunstall/nova/pkg/lex"
 
 type Expr interface {
+	Node
 	expr()
 }
 
 type UnaryExpr struct {
+	Span
+
 	Op   lex.Token
 	Expr Expr
+	// Mut is set for a mutable address-of, such as '&mut x'.
+	Mut bool
 }
 
 func (n *UnaryExpr) expr() {}
 
 type BinaryExpr struct {
+	Span
+
 	Op lex.Token
 	L  Expr
 	R  Expr
@@ -22,26 +29,74 @@ type BinaryExpr struct {
 func (n *BinaryExpr) expr() {}
 
 type VarExpr struct {
+	Span
+
 	Name *Ident
 }
 
 func (n *VarExpr) expr() {}
 
 type AssignExpr struct {
-	L Expr
-	R Expr
+	Span
+
+	// Op is the binary operator of a compound assignment, such as
+	// [lex.ADD] for '+=', or [lex.ILLEGAL] for a simple assignment.
+	//
+	// 'x++' and 'x--' are parsed as 'x += 1' and 'x -= 1'.
+	Op lex.Token
+	// Tok is the assignment token as written, such as [lex.ADD_ASSIGN],
+	// or [lex.INC] and [lex.DEC] for increments and decrements.
+	Tok lex.Token
+	L   Expr
+	R   Expr
 }
 
 func (n *AssignExpr) expr() {}
 
+// CallExpr is a function call. A method call, such as 's.sum()', has a
+// receiver, and a static call, such as 'MyStruct::new()', has a type.
 type CallExpr struct {
+	Span
+
+	Recv Expr
+	Type *Ident
 	Func *Ident
 	Args []Expr
 }
 
 func (n *CallExpr) expr() {}
 
+// CompositeLit constructs a struct value, such as 'MyStruct{ a: 1, b: 2 }'.
+type CompositeLit struct {
+	Span
+
+	Type   *Ident
+	Fields []*FieldValue
+}
+
+func (n *CompositeLit) expr() {}
+
+// FieldValue initialises a field in a [CompositeLit].
+type FieldValue struct {
+	Span
+
+	Name  *Ident
+	Value Expr
+}
+
+// SelectorExpr selects a field, such as 's.field'.
+type SelectorExpr struct {
+	Span
+
+	X   Expr
+	Sel *Ident
+}
+
+func (n *SelectorExpr) expr() {}
+
 type BasicLitExpr struct {
+	Span
+
 	Kind  lex.Token
 	Value string
 }
@@ -49,6 +104,8 @@ type BasicLitExpr struct {
 func (n *BasicLitExpr) expr() {}
 
 type Ident struct {
+	Span
+
 	Name string
 }
 
@@ -1,5 +1,8 @@
 package syntax
 
 type File struct {
+	// Name is the name of the source file.
+	Name string
+
 	Decls []Decl
 }
@@ -0,0 +1,21 @@
+package syntax
+
+import "github.com/andydunstall/nova/pkg/lex"
+
+// Node is implemented by all AST nodes.
+type Node interface {
+	// Pos returns the position of the first character of the node.
+	Pos() lex.Position
+	// End returns the position immediately after the node.
+	End() lex.Position
+}
+
+// Span is the source range of a node, embedded in each node type.
+type Span struct {
+	From lex.Position
+	To   lex.Position
+}
+
+func (s Span) Pos() lex.Position { return s.From }
+
+func (s Span) End() lex.Position { return s.To }
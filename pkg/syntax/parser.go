@@ -3,23 +3,90 @@ package syntax
 import (
 	"fmt"
 
-	"github.com/andydunstall/nova/pkg/assert"
+	"github.com/andydunstall/nova/pkg/diag"
 	"github.com/andydunstall/nova/pkg/lex"
 )
 
+// DefaultMaxErrors is the default number of syntax errors reported before
+// parsing stops.
+const DefaultMaxErrors = 10
+
+// Option configures the parser.
+type Option func(p *parser)
+
+// WithMaxErrors sets the number of syntax errors reported before parsing
+// stops. Zero means unlimited.
+func WithMaxErrors(n int) Option {
+	return func(p *parser) {
+		p.maxErrors = n
+	}
+}
+
 // Parse parses the token stream into an AST.
-func Parse(scanner *lex.Scanner) (*File, error) {
+//
+// On a syntax error the parser skips to the next statement or declaration
+// and continues, so all errors in the file are returned as a [diag.List].
+// The returned file contains the nodes that parsed successfully.
+func Parse(scanner *lex.Scanner, opts ...Option) (f *File, err error) {
 	p := newParser(scanner)
-	f := p.parseFile()
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(errorLimit); !ok {
+				panic(r)
+			}
+			// Return the declarations parsed before the limit.
+			f = &File{
+				Name:  p.pos.Filename,
+				Decls: p.decls,
+			}
+		}
+		err = p.diags.Err()
+	}()
+
+	f = p.parseFile()
 	return f, nil
 }
 
+// bailout is panicked on a syntax error to unwind to the enclosing
+// statement or declaration.
+type bailout struct{}
+
+// errorLimit is panicked when the maximum number of errors is reached.
+type errorLimit struct{}
+
 type parser struct {
 	tok lex.Token
 	lit string
+	// pos is the position of the current token.
+	pos lex.Position
+	// end is the position immediately after the current token.
+	end lex.Position
+	// prevEnd is the position immediately after the previous token.
+	prevEnd lex.Position
 
 	scanner *lex.Scanner
 
+	// decls are the top level declarations parsed so far.
+	decls []Decl
+
+	// exprLev is the nesting level of parentheses in the current
+	// expression, or -1 in a control clause without parentheses, where a
+	// '{' starts the body rather than a composite literal.
+	exprLev int
+	// afterExpr is set if the current token follows an expression, so an
+	// assignment token is an assignment used as an expression.
+	afterExpr bool
+
+	diags     diag.List
+	maxErrors int
+	// syncPos is the position of the last synchronisation, used to ensure
+	// the parser makes progress when recovering from errors.
+	syncPos lex.Position
+
 	line   int
 	indent int
 	debug  bool
@@ -27,32 +94,40 @@ type parser struct {
 
 func newParser(scanner *lex.Scanner) *parser {
 	return &parser{
-		scanner: scanner,
-		line:    1,
-		debug:   true,
+		scanner:   scanner,
+		maxErrors: DefaultMaxErrors,
+		syncPos:   lex.Position{Offset: -1},
+		line:      1,
+		debug:     false,
 	}
 }
 
 func (p *parser) parseFile() *File {
-	// TODO(andydunstall): Handle position and errors.
-	p.tok, p.lit, _, _ = p.scanner.Scan()
+	p.scan()
 
 	if p.debug {
 		defer un(trace(p, "File"))
 	}
 
-	var decls []Decl
 	for p.tok != lex.EOF {
-		decls = append(decls, p.parseDecl())
+		if decl := p.parseDeclRecover(); decl != nil {
+			p.decls = append(p.decls, decl)
+		}
 	}
 
 	return &File{
-		Decls: decls,
+		Name:  p.pos.Filename,
+		Decls: p.decls,
 	}
 }
 
 // Expressions.
 
+// parseExpr parses an expression containing binary operators with a
+// precedence greater than minPrec, using precedence climbing.
+//
+// Assignments aren't included, since they can only be used as statements
+// (see parseSimpleExpr).
 func (p *parser) parseExpr(minPrec int) Expr {
 	if p.debug {
 		defer un(trace(p, "Expr"))
@@ -64,26 +139,67 @@ func (p *parser) parseExpr(minPrec int) Expr {
 		if prec <= minPrec {
 			break
 		}
-
-		if p.tok == lex.ASSIGN {
-			l = p.parseAssignExpr(l, prec)
-		} else {
-			l = p.parseBinaryExpr(l, prec)
-		}
+		l = p.parseBinaryExpr(l, prec)
 	}
 
+	p.afterExpr = true
 	return l
 }
 
-func (p *parser) parseAssignExpr(l Expr, prec int) *AssignExpr {
+// parseAssignExpr parses an assignment to l. Assignment is right
+// associative, so 'a = b = c' assigns 'b = c' to a.
+func (p *parser) parseAssignExpr(l Expr) *AssignExpr {
 	if p.debug {
 		defer un(trace(p, "AssignExpr"))
 	}
 
-	p.expect(lex.ASSIGN)
+	tok, op := p.tok, p.tok.AssignOp()
+	p.next()
+	var r Expr = p.parseExpr(0)
+	if p.tok.IsAssign() {
+		r = p.parseAssignExpr(r)
+	}
+	return &AssignExpr{
+		Span: p.span(l.Pos()),
+		Op:   op,
+		Tok:  tok,
+		L:    l,
+		R:    r,
+	}
+}
+
+// parseSimpleExpr parses an expression, an assignment, or an increment or
+// decrement such as 'x++'. Assignments, increments and decrements can only
+// be used as statements.
+func (p *parser) parseSimpleExpr() Expr {
+	if p.debug {
+		defer un(trace(p, "SimpleExpr"))
+	}
+
+	expr := p.parseExpr(0)
+	if p.tok.IsAssign() {
+		return p.parseAssignExpr(expr)
+	}
+	if p.tok != lex.INC && p.tok != lex.DEC {
+		return expr
+	}
+
+	tok, op := p.tok, lex.ADD
+	if tok == lex.DEC {
+		op = lex.SUB
+	}
+	one := &BasicLitExpr{
+		Span:  Span{From: p.pos, To: p.end},
+		Kind:  lex.INT,
+		Value: "1",
+	}
+	p.next()
 	return &AssignExpr{
-		L: l,
-		R: p.parseExpr(prec),
+		Span: p.span(expr.Pos()),
+		Op:   op,
+		Tok:  tok,
+		L:    expr,
+		R:    one,
 	}
 }
 
@@ -95,21 +211,33 @@ func (p *parser) parseBinaryExpr(l Expr, prec int) *BinaryExpr {
 	op := p.tok
 	p.next()
 
+	r := p.parseExpr(prec + 1)
 	return &BinaryExpr{
-		Op: op,
-		L:  l,
-		R:  p.parseExpr(prec + 1),
+		Span: p.span(l.Pos()),
+		Op:   op,
+		L:    l,
+		R:    r,
 	}
 }
 
-func (p *parser) parseCallExpr(name *Ident) *CallExpr {
+// parseCallExpr parses the arguments of a call to name. recv is the
+// receiver of a method call, and typ is the type of a static call.
+func (p *parser) parseCallExpr(recv Expr, typ *Ident, name *Ident) *CallExpr {
 	if p.debug {
 		defer un(trace(p, "CallExpr"))
 	}
 
+	pos := name.Pos()
+	if recv != nil {
+		pos = recv.Pos()
+	} else if typ != nil {
+		pos = typ.Pos()
+	}
+
 	var args []Expr
 
 	p.expect(lex.LPAREN)
+	p.exprLev++
 	for p.tok != lex.RPAREN {
 		args = append(args, p.parseExpr(0))
 
@@ -117,51 +245,147 @@ func (p *parser) parseCallExpr(name *Ident) *CallExpr {
 			p.expect(lex.COMMA)
 		}
 	}
+	p.exprLev--
 	p.expect(lex.RPAREN)
 
 	return &CallExpr{
+		Span: p.span(pos),
+		Recv: recv,
+		Type: typ,
 		Func: name,
 		Args: args,
 	}
 }
 
+func (p *parser) parseCompositeLit(typ *Ident) *CompositeLit {
+	if p.debug {
+		defer un(trace(p, "CompositeLit"))
+	}
+
+	var fields []*FieldValue
+
+	p.expect(lex.LBRACE)
+	for p.tok != lex.RBRACE {
+		name := p.parseIdent()
+		p.expect(lex.COLON)
+		value := p.parseExpr(0)
+		fields = append(fields, &FieldValue{
+			Span:  p.span(name.Pos()),
+			Name:  name,
+			Value: value,
+		})
+
+		if p.tok != lex.RBRACE {
+			p.expect(lex.COMMA)
+		}
+	}
+	p.expect(lex.RBRACE)
+
+	return &CompositeLit{
+		Span:   p.span(typ.Pos()),
+		Type:   typ,
+		Fields: fields,
+	}
+}
+
 func (p *parser) parseFactor() Expr {
 	if p.debug {
 		defer un(trace(p, "Factor"))
 	}
 
+	x := p.parseOperand()
+	for p.tok == lex.PERIOD {
+		p.next()
+		sel := p.parseIdent()
+		if p.tok == lex.LPAREN {
+			x = p.parseCallExpr(x, nil, sel)
+			continue
+		}
+		x = &SelectorExpr{
+			Span: p.span(x.Pos()),
+			X:    x,
+			Sel:  sel,
+		}
+	}
+	return x
+}
+
+func (p *parser) parseOperand() Expr {
+	if p.debug {
+		defer un(trace(p, "Operand"))
+	}
+
+	pos := p.pos
 	switch p.tok {
-	case lex.INT:
-		f := &BasicLitExpr{
-			Kind:  p.tok,
-			Value: p.lit,
+	case lex.INT, lex.BOOL:
+		kind, value := p.tok, p.lit
+		p.next()
+		return &BasicLitExpr{
+			Span:  p.span(pos),
+			Kind:  kind,
+			Value: value,
 		}
+	case lex.NULL:
 		p.next()
-		return f
+		return &BasicLitExpr{
+			Span:  p.span(pos),
+			Kind:  lex.NULL,
+			Value: "null",
+		}
 	case lex.SUB, lex.TILDE, lex.NOT:
+		// Prefix operators bind tighter than any binary operator, so
+		// '-a + b' is '(-a) + b'.
 		op := p.tok
 		p.next()
-		expr := p.parseExpr(0)
+		expr := p.parseFactor()
 		return &UnaryExpr{
+			Span: p.span(pos),
 			Op:   op,
 			Expr: expr,
 		}
+	case lex.AND, lex.MUL:
+		// The operand of '&' and '*' includes any field accesses, such as
+		// '&s.field', and binds tighter than any binary operator, so '*p = v'
+		// assigns to '*p'.
+		op := p.tok
+		p.next()
+		mut := false
+		if op == lex.AND && p.tok == lex.MUT {
+			p.next()
+			mut = true
+		}
+		expr := p.parseFactor()
+		return &UnaryExpr{
+			Span: p.span(pos),
+			Op:   op,
+			Expr: expr,
+			Mut:  mut,
+		}
 	case lex.LPAREN:
 		p.next()
+		p.exprLev++
 		expr := p.parseExpr(0)
+		p.exprLev--
 		p.expect(lex.RPAREN)
 		return expr
 	case lex.IDENT:
 		name := p.parseIdent()
-		if p.tok == lex.LPAREN {
-			return p.parseCallExpr(name)
+		if p.tok == lex.COLONCOLON {
+			p.next()
+			return p.parseCallExpr(nil, name, p.parseIdent())
+		} else if p.tok == lex.LPAREN {
+			return p.parseCallExpr(nil, nil, name)
+		} else if p.tok == lex.LBRACE && p.exprLev >= 0 {
+			return p.parseCompositeLit(name)
 		} else {
 			return &VarExpr{
+				Span: name.Span,
 				Name: name,
 			}
 		}
 	default:
-		panic("unknown: " + p.tok.String())
+		p.errorExpected("expression")
+		return nil // Unreachable.
 	}
 }
 
@@ -183,6 +407,14 @@ func (p *parser) parseStmt() (s Stmt) {
 		s = p.parseIfStmt()
 	case lex.LOOP:
 		s = p.parseLoopStmt()
+	case lex.FOR:
+		s = p.parseForStmt()
+	case lex.IDENT:
+		if p.peek() == lex.COLON {
+			s = p.parseLabeledStmt()
+		} else {
+			s = p.parseExprStmt()
+		}
 	case lex.BREAK:
 		s = p.parseBreakStmt()
 	case lex.CONTINUE:
@@ -198,13 +430,19 @@ func (p *parser) parseBlockStmt() *BlockStmt {
 		defer un(trace(p, "BlockStmt"))
 	}
 
+	pos := p.pos
 	p.expect(lex.LBRACE)
 	var list []Stmt
-	for p.tok != lex.RBRACE && p.tok != lex.EOF {
-		list = append(list, p.parseStmt())
+	// Stop at 'fn' or 'struct' since a nested declaration means the closing
+	// brace is missing.
+	for p.tok != lex.RBRACE && p.tok != lex.EOF && p.tok != lex.FN && p.tok != lex.STRUCT {
+		if stmt := p.parseStmtRecover(); stmt != nil {
+			list = append(list, stmt)
+		}
 	}
 	p.expect(lex.RBRACE)
 	return &BlockStmt{
+		Span: p.span(pos),
 		List: list,
 	}
 }
@@ -214,11 +452,16 @@ func (p *parser) parseReturnStmt() *ReturnStmt {
 		defer un(trace(p, "ReturnStmt"))
 	}
 
+	pos := p.pos
 	p.expect(lex.RETURN)
 
-	expr := p.parseExpr(0)
+	var expr Expr
+	if p.tok != lex.SEMICOLON {
+		expr = p.parseExpr(0)
+	}
 	p.expect(lex.SEMICOLON)
 	return &ReturnStmt{
+		Span:   p.span(pos),
 		Result: expr,
 	}
 }
@@ -228,10 +471,11 @@ func (p *parser) parseExprStmt() *ExprStmt {
 		defer un(trace(p, "ExprStmt"))
 	}
 
-	expr := p.parseExpr(0)
+	expr := p.parseSimpleExpr()
 	p.expect(lex.SEMICOLON)
 	return &ExprStmt{
-		E: expr,
+		Span: p.span(expr.Pos()),
+		E:    expr,
 	}
 }
 
@@ -240,8 +484,10 @@ func (p *parser) parseDeclStmt() *DeclStmt {
 		defer un(trace(p, "DeclStmt"))
 	}
 
+	decl := p.parseDecl()
 	return &DeclStmt{
-		Decl: p.parseDecl(),
+		Span: Span{From: decl.Pos(), To: decl.End()},
+		Decl: decl,
 	}
 }
 
@@ -250,6 +496,7 @@ func (p *parser) parseIfStmt() *IfStmt {
 		defer un(trace(p, "IfStmt"))
 	}
 
+	pos := p.pos
 	p.expect(lex.IF)
 	p.expect(lex.LPAREN)
 	cond := p.parseExpr(0)
@@ -263,6 +510,7 @@ func (p *parser) parseIfStmt() *IfStmt {
 	}
 
 	return &IfStmt{
+		Span: p.span(pos),
 		Cond: cond,
 		Then: thenStmt,
 		Else: elseStmt,
@@ -274,26 +522,140 @@ func (p *parser) parseLoopStmt() *LoopStmt {
 		defer un(trace(p, "LoopStmt"))
 	}
 
+	pos := p.pos
 	p.expect(lex.LOOP)
-	p.expect(lex.LPAREN)
-	cond := p.parseExpr(0)
-	p.expect(lex.RPAREN)
+	var cond Expr
+	if p.tok == lex.LPAREN {
+		p.next()
+		cond = p.parseExpr(0)
+		p.expect(lex.RPAREN)
+	}
 	body := p.parseBlockStmt()
 	return &LoopStmt{
+		Span: p.span(pos),
+		Cond: cond,
+		Body: body,
+	}
+}
+
+// parseForStmt parses either a C style for loop or a range loop.
+func (p *parser) parseForStmt() Stmt {
+	if p.debug {
+		defer un(trace(p, "ForStmt"))
+	}
+
+	pos := p.pos
+	p.expect(lex.FOR)
+	if p.tok != lex.LPAREN {
+		return p.parseRangeStmt(pos)
+	}
+	p.next()
+
+	var init Stmt
+	switch p.tok {
+	case lex.SEMICOLON:
+		p.next()
+	case lex.LET:
+		// The declaration includes the ';'.
+		init = p.parseDeclStmt()
+	default:
+		init = p.parseExprStmt()
+	}
+
+	var cond Expr
+	if p.tok != lex.SEMICOLON {
+		cond = p.parseExpr(0)
+	}
+	p.expect(lex.SEMICOLON)
+
+	var post Expr
+	if p.tok != lex.RPAREN {
+		post = p.parseSimpleExpr()
+	}
+	p.expect(lex.RPAREN)
+
+	body := p.parseBlockStmt()
+	return &ForStmt{
+		Span: p.span(pos),
+		Init: init,
 		Cond: cond,
+		Post: post,
 		Body: body,
 	}
 }
 
+// parseRangeStmt parses a range loop, such as 'for i in 0..n {}', after the
+// 'for' keyword.
+func (p *parser) parseRangeStmt(pos lex.Position) *RangeStmt {
+	name := p.parseIdent()
+	p.expect(lex.IN)
+
+	// The bounds aren't parenthesised, so a '{' starts the body.
+	lev := p.exprLev
+	p.exprLev = -1
+	low := p.parseExpr(0)
+	p.expect(lex.DOTDOT)
+	high := p.parseExpr(0)
+	p.exprLev = lev
+
+	body := p.parseBlockStmt()
+	return &RangeStmt{
+		Span: p.span(pos),
+		Var:  name,
+		Low:  low,
+		High: high,
+		Body: body,
+	}
+}
+
+// parseLabeledStmt parses a labeled loop, such as 'outer: loop (...) {}'.
+func (p *parser) parseLabeledStmt() Stmt {
+	if p.debug {
+		defer un(trace(p, "LabeledStmt"))
+	}
+
+	pos := p.pos
+	label := p.parseIdent()
+	p.expect(lex.COLON)
+	switch p.tok {
+	case lex.LOOP:
+		stmt := p.parseLoopStmt()
+		stmt.Span = p.span(pos)
+		stmt.Label = label
+		return stmt
+	case lex.FOR:
+		switch stmt := p.parseForStmt().(type) {
+		case *ForStmt:
+			stmt.Span = p.span(pos)
+			stmt.Label = label
+			return stmt
+		case *RangeStmt:
+			stmt.Span = p.span(pos)
+			stmt.Label = label
+			return stmt
+		}
+	}
+	p.errorExpected("loop after label")
+	return nil // Unreachable.
+}
+
 func (p *parser) parseBreakStmt() *BreakStmt {
 	if p.debug {
 		defer un(trace(p, "BreakStmt"))
 	}
 
+	pos := p.pos
 	p.expect(lex.BREAK)
+	var label *Ident
+	if p.tok == lex.IDENT {
+		label = p.parseIdent()
+	}
 	p.expect(lex.SEMICOLON)
 
-	return &BreakStmt{}
+	return &BreakStmt{
+		Span:  p.span(pos),
+		Label: label,
+	}
 }
 
 func (p *parser) parseContinueStmt() *ContinueStmt {
@@ -301,10 +663,18 @@ func (p *parser) parseContinueStmt() *ContinueStmt {
 		defer un(trace(p, "ContinueStmt"))
 	}
 
+	pos := p.pos
 	p.expect(lex.CONTINUE)
+	var label *Ident
+	if p.tok == lex.IDENT {
+		label = p.parseIdent()
+	}
 	p.expect(lex.SEMICOLON)
 
-	return &ContinueStmt{}
+	return &ContinueStmt{
+		Span:  p.span(pos),
+		Label: label,
+	}
 }
 
 // Declaration.
@@ -319,8 +689,11 @@ func (p *parser) parseDecl() Decl {
 		return p.parseFuncDecl()
 	case lex.LET:
 		return p.parseVarDecl()
+	case lex.STRUCT:
+		return p.parseStructDecl()
 	default:
-		panic("unsupported decl")
+		p.errorExpected("declaration")
+		return nil // Unreachable.
 	}
 }
 
@@ -331,22 +704,42 @@ func (p *parser) parseFuncDecl() *FuncDecl {
 
 	var funcDecl FuncDecl
 
+	pos := p.pos
 	p.expect(lex.FN)
 	funcDecl.Name = p.parseIdent()
+	if p.tok == lex.COLONCOLON {
+		p.next()
+		funcDecl.Recv = funcDecl.Name
+		funcDecl.Name = p.parseIdent()
+	}
 
 	p.expect(lex.LPAREN)
 
 	for p.tok != lex.RPAREN {
 		var param FuncParam
 
+		paramPos := p.pos
+		if p.tok == lex.MUT {
+			p.next()
+			param.Mut = true
+		}
 		param.Name = p.parseIdent()
 
-		// Parse type.
-		p.expect(lex.COLON)
-		typ := p.parseIdent()
-		param.Type = typ.Name
+		// The receiver of a method may be declared as the first parameter,
+		// such as 'mut self'.
+		if param.Name.Name == "self" && p.tok != lex.COLON && len(funcDecl.Params) == 0 && funcDecl.Self == nil {
+			funcDecl.Self = &SelfParam{
+				Span: p.span(paramPos),
+				Mut:  param.Mut,
+			}
+		} else {
+			// Parse type.
+			p.expect(lex.COLON)
+			param.Type = p.parseType()
+			param.Span = p.span(paramPos)
 
-		funcDecl.Params = append(funcDecl.Params, param)
+			funcDecl.Params = append(funcDecl.Params, param)
+		}
 
 		if p.tok != lex.RPAREN {
 			p.expect(lex.COMMA)
@@ -357,53 +750,274 @@ func (p *parser) parseFuncDecl() *FuncDecl {
 	if p.tok == lex.ARROW {
 		p.next()
 
-		typ := p.parseIdent()
-		funcDecl.ReturnType = typ.Name
+		funcDecl.ReturnType = p.parseType()
 	}
 
 	funcDecl.Body = p.parseBlockStmt()
+	funcDecl.Span = p.span(pos)
 	return &funcDecl
 }
 
+// parseStructDecl parses a struct declaration. Fields are written as
+// 'name type' or 'name: type', optionally separated by ',' or ';'.
+func (p *parser) parseStructDecl() *StructDecl {
+	if p.debug {
+		defer un(trace(p, "StructDecl"))
+	}
+
+	pos := p.pos
+	p.expect(lex.STRUCT)
+	name := p.parseIdent()
+
+	var fields []*Field
+	p.expect(lex.LBRACE)
+	for p.tok != lex.RBRACE && p.tok != lex.EOF {
+		fieldName := p.parseIdent()
+		if p.tok == lex.COLON {
+			p.next()
+		}
+		typ := p.parseType()
+		fields = append(fields, &Field{
+			Span: p.span(fieldName.Pos()),
+			Name: fieldName,
+			Type: typ,
+		})
+
+		if p.tok == lex.COMMA || p.tok == lex.SEMICOLON {
+			p.next()
+		}
+	}
+	p.expect(lex.RBRACE)
+
+	// The trailing ';' is optional.
+	if p.tok == lex.SEMICOLON {
+		p.next()
+	}
+
+	return &StructDecl{
+		Span:   p.span(pos),
+		Name:   name,
+		Fields: fields,
+	}
+}
+
 func (p *parser) parseVarDecl() *VarDecl {
 	if p.debug {
 		defer un(trace(p, "VarDecl"))
 	}
 
+	pos := p.pos
 	p.expect(lex.LET)
+	mut := false
+	if p.tok == lex.MUT {
+		p.next()
+		mut = true
+	}
 	name := p.parseIdent()
 
-	// Parse type.
-	p.expect(lex.COLON)
-	typ := p.parseIdent()
+	// Parse the optional type.
+	var typ Type
+	if p.tok == lex.COLON {
+		p.next()
+		typ = p.parseType()
+	}
 
 	p.expect(lex.ASSIGN)
 	expr := p.parseExpr(0)
 	p.expect(lex.SEMICOLON)
 
 	return &VarDecl{
+		Span: p.span(pos),
+		Mut:  mut,
 		Name: name,
 		Expr: expr,
-		Type: typ.Name,
+		Type: typ,
+	}
+}
+
+// Types.
+
+func (p *parser) parseType() Type {
+	if p.debug {
+		defer un(trace(p, "Type"))
+	}
+
+	pos := p.pos
+	switch p.tok {
+	case lex.MUL:
+		p.next()
+		mut := false
+		if p.tok == lex.MUT {
+			p.next()
+			mut = true
+		}
+		elem := p.parseType()
+		return &PointerType{
+			Span: p.span(pos),
+			Mut:  mut,
+			Elem: elem,
+		}
+	case lex.IDENT:
+		name := p.parseIdent()
+		return &NamedType{
+			Span: name.Span,
+			Name: name,
+		}
+	default:
+		p.errorExpected("type")
+		return nil // Unreachable.
 	}
 }
 
 func (p *parser) parseIdent() *Ident {
+	pos := p.pos
 	name := p.lit
 	p.expect(lex.IDENT)
 	return &Ident{
+		Span: p.span(pos),
 		Name: name,
 	}
 }
 
 func (p *parser) expect(tok lex.Token) {
+	if p.tok != tok && p.tok.IsAssign() && p.afterExpr {
+		// The expression is followed by an assignment in a context where
+		// assignments aren't allowed, such as 'if (a = b)'.
+		span := diag.Span{Start: p.pos, End: p.end}
+		p.addError(
+			diag.Errorf(span, "assignment is only allowed as a statement").
+				WithLabel("expected '"+tok.String()+"'").
+				WithExpected("'"+tok.String()+"'", p.tokDesc()),
+		)
+		panic(bailout{})
+	}
 	if p.tok != tok {
-		assert.Panicf("unexpected token: %s; wanted: %s", p.tok, tok)
+		p.errorExpected("'" + tok.String() + "'")
 		return // Unreachable.
 	}
 	p.next()
 }
 
+// Error handling.
+
+// parseStmtRecover parses a statement, returning nil if the statement
+// contains a syntax error.
+func (p *parser) parseStmtRecover() (s Stmt) {
+	lev := p.exprLev
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(bailout); !ok {
+				panic(r)
+			}
+			p.exprLev = lev
+			p.syncStmt()
+			s = nil
+		}
+	}()
+	return p.parseStmt()
+}
+
+// parseDeclRecover parses a top level declaration, returning nil if the
+// declaration contains a syntax error.
+func (p *parser) parseDeclRecover() (d Decl) {
+	lev := p.exprLev
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(bailout); !ok {
+				panic(r)
+			}
+			p.exprLev = lev
+			p.syncDecl()
+			d = nil
+		}
+	}()
+	return p.parseDecl()
+}
+
+// syncStmt skips to the start of the next statement. A ';' is consumed,
+// whereas a '}' or keyword starting a statement or declaration is left as
+// the next token.
+func (p *parser) syncStmt() {
+	p.sync(true, func(tok lex.Token) bool {
+		switch tok {
+		case lex.RBRACE, lex.LET, lex.IF, lex.LOOP, lex.FOR, lex.RETURN, lex.BREAK, lex.CONTINUE, lex.FN, lex.STRUCT:
+			return true
+		default:
+			return false
+		}
+	})
+}
+
+// syncDecl skips to the start of the next top level declaration.
+func (p *parser) syncDecl() {
+	p.sync(false, func(tok lex.Token) bool {
+		return tok == lex.FN || tok == lex.LET || tok == lex.STRUCT
+	})
+}
+
+// sync skips tokens until stop returns true, or if semicolon is set, until
+// after the next ';'.
+func (p *parser) sync(semicolon bool, stop func(tok lex.Token) bool) {
+	// If the parser hasn't moved since the last sync, skip the current
+	// token to avoid reporting the same error forever.
+	if p.pos.Offset == p.syncPos.Offset && p.tok != lex.EOF {
+		p.next()
+	}
+
+	for p.tok != lex.EOF {
+		if semicolon && p.tok == lex.SEMICOLON {
+			p.next()
+			break
+		}
+		if stop(p.tok) {
+			break
+		}
+		p.next()
+	}
+	p.syncPos = p.pos
+}
+
+// errorExpected records an unexpected token error and bails out of the
+// current statement or declaration.
+func (p *parser) errorExpected(expected string) {
+	span := diag.Span{Start: p.pos, End: p.end}
+	p.addError(
+		diag.Errorf(span, "expected %s, found %s", expected, p.tokDesc()).
+			WithLabel("expected "+expected).
+			WithExpected(expected, p.tokDesc()),
+	)
+	panic(bailout{})
+}
+
+// addError records the error, ignoring multiple errors at the same
+// position as only the first is useful.
+func (p *parser) addError(d *diag.Diagnostic) {
+	if n := len(p.diags); n > 0 && p.diags[n-1].Span.Start.Offset == d.Span.Start.Offset {
+		return
+	}
+
+	p.diags.Add(d)
+	if p.maxErrors > 0 && len(p.diags) >= p.maxErrors {
+		panic(errorLimit{})
+	}
+}
+
+// tokDesc returns a description of the current token for errors.
+func (p *parser) tokDesc() string {
+	switch {
+	case p.tok == lex.EOF:
+		return "EOF"
+	case p.tok == lex.IDENT:
+		return "identifier " + p.lit
+	case p.tok.IsLiteral():
+		return "literal " + p.lit
+	case p.tok == lex.ILLEGAL:
+		return "'" + p.lit + "'"
+	default:
+		return "'" + p.tok.String() + "'"
+	}
+}
+
 func (p *parser) next() {
 	if p.debug {
 		s := p.tok.String()
@@ -417,8 +1031,36 @@ func (p *parser) next() {
 		}
 	}
 
-	// TODO(andydunstall): Handle position and errors.
-	p.tok, p.lit, _, _ = p.scanner.Scan()
+	p.afterExpr = false
+	p.scan()
+}
+
+// peek returns the token following the current token, without consuming
+// it. Any scan error is reported when the token is consumed.
+func (p *parser) peek() lex.Token {
+	scanner := *p.scanner
+	tok, _, _, _ := scanner.Scan()
+	return tok
+}
+
+func (p *parser) scan() {
+	p.prevEnd = p.scanner.Pos()
+
+	var err error
+	p.tok, p.lit, p.pos, err = p.scanner.Scan()
+	p.end = p.scanner.Pos()
+	if err != nil {
+		p.addError(diag.Errorf(diag.Span{Start: p.pos, End: p.end}, "%s", err))
+	}
+}
+
+// span returns the span from the given position to the end of the previous
+// token.
+func (p *parser) span(from lex.Position) Span {
+	return Span{
+		From: from,
+		To:   p.prevEnd,
+	}
 }
 
 func (p *parser) precedence(tok lex.Token) int {
@@ -427,16 +1069,22 @@ func (p *parser) precedence(tok lex.Token) int {
 		return 50
 	case lex.ADD, lex.SUB:
 		return 45
+	case lex.SHL, lex.SHR:
+		return 40
 	case lex.LSS, lex.LEQ, lex.GTR, lex.GEQ:
 		return 35
 	case lex.EQL, lex.NEQ:
 		return 30
+	case lex.AND:
+		return 25
+	case lex.XOR:
+		return 20
+	case lex.OR:
+		return 15
 	case lex.LAND:
 		return 10
 	case lex.LOR:
 		return 5
-	case lex.ASSIGN:
-		return 1
 	default:
 		return -1
 	}
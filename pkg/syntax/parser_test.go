@@ -0,0 +1,404 @@
+package syntax_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/andydunstall/nova/pkg/diag"
+	"github.com/andydunstall/nova/pkg/lex"
+	"github.com/andydunstall/nova/pkg/syntax"
+)
+
+func TestParse_Positions(t *testing.T) {
+	f, err := parseFile("\treturn a + f(1, 2);")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := f.Decls[0].(*syntax.FuncDecl)
+	ret := fn.Body.List[0].(*syntax.ReturnStmt)
+	bin := ret.Result.(*syntax.BinaryExpr)
+	call := bin.R.(*syntax.CallExpr)
+
+	tests := []struct {
+		name string
+		node syntax.Node
+		pos  string
+		end  string
+	}{
+		{"func", fn, "test.nv:1:1", "test.nv:3:2"},
+		{"name", fn.Name, "test.nv:1:4", "test.nv:1:5"},
+		{"body", fn.Body, "test.nv:1:8", "test.nv:3:2"},
+		{"return", ret, "test.nv:2:2", "test.nv:2:21"},
+		{"binary", bin, "test.nv:2:9", "test.nv:2:20"},
+		{"var", bin.L, "test.nv:2:9", "test.nv:2:10"},
+		{"call", call, "test.nv:2:13", "test.nv:2:20"},
+		{"arg", call.Args[1], "test.nv:2:18", "test.nv:2:19"},
+	}
+	for _, tt := range tests {
+		if got := tt.node.Pos().String(); got != tt.pos {
+			t.Errorf("%s: got pos %s, want %s", tt.name, got, tt.pos)
+		}
+		if got := tt.node.End().String(); got != tt.end {
+			t.Errorf("%s: got end %s, want %s", tt.name, got, tt.end)
+		}
+	}
+}
+
+func TestParse_Recovery(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		errs  []string
+		decls []string
+	}{
+		{
+			"statements",
+			"fn a() {\n\tlet x: i32 = ;\n\treturn 1 +;\n\treturn 2;\n}\n",
+			[]string{
+				"test.nv:2:15: expected expression, found ';'",
+				"test.nv:3:12: expected expression, found ';'",
+			},
+			[]string{"a"},
+		},
+		{
+			"missing semicolon",
+			"fn a() -> i32 {\n\treturn 2\n}\n\nfn b() {}\n",
+			[]string{"test.nv:3:1: expected ';', found '}'"},
+			[]string{"a", "b"},
+		},
+		{
+			"declarations",
+			"fn a( {\n}\n\nfn b() {}\n\nfn c() {\n\tlet = 1;\n}\n",
+			[]string{
+				"test.nv:1:7: expected 'IDENT', found '{'",
+				"test.nv:7:6: expected 'IDENT', found '='",
+			},
+			[]string{"b", "c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := syntax.Parse(lex.NewScanner("test.nv", []byte(tt.src)))
+			checkErrors(t, err, tt.errs)
+			checkDecls(t, f, tt.decls)
+		})
+	}
+}
+
+func TestParse_MaxErrors(t *testing.T) {
+	src := `
+fn a() {}
+
+fn b() {
+	return +;
+	return +;
+	return +;
+}
+
+fn c() {}
+`
+	tests := []struct {
+		max   int
+		errs  int
+		decls []string
+	}{
+		{0, 3, []string{"a", "b", "c"}},
+		{1, 1, []string{"a"}},
+		{2, 2, []string{"a"}},
+		{3, 3, []string{"a"}},
+		{4, 3, []string{"a", "b", "c"}},
+	}
+	for _, tt := range tests {
+		f, err := syntax.Parse(
+			lex.NewScanner("test.nv", []byte(src)), syntax.WithMaxErrors(tt.max),
+		)
+		var list diag.List
+		if !errors.As(err, &list) {
+			t.Fatalf("max %d: got error %v, want error list", tt.max, err)
+		}
+		if len(list) != tt.errs {
+			t.Errorf("max %d: got %d errors, want %d", tt.max, len(list), tt.errs)
+		}
+		checkDecls(t, f, tt.decls)
+	}
+}
+
+func TestParse_BareReturn(t *testing.T) {
+	f, err := parseFile("\treturn;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := f.Decls[0].(*syntax.FuncDecl)
+	ret := fn.Body.List[0].(*syntax.ReturnStmt)
+	if ret.Result != nil {
+		t.Errorf("got result %#v, want nil", ret.Result)
+	}
+	if got := ret.End().Column; got != 9 {
+		t.Errorf("got end column %d, want 9", got)
+	}
+}
+
+func TestParse_ExprShape(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		// Prefix operators bind tighter than binary operators.
+		{"-a + b", "((-a) + b)"},
+		{"-a * b", "((-a) * b)"},
+		{"a - -b", "(a - (-b))"},
+		{"~a & b", "((~a) & b)"},
+		{"!x && y", "((!x) && y)"},
+		{"!x || !y", "((!x) || (!y))"},
+		{"- -a", "(-(-a))"},
+		{"-f(a) + b", "((-f(a)) + b)"},
+		{"*p + 1", "((*p) + 1)"},
+		{"*p * *q", "((*p) * (*q))"},
+		{"a & *p", "(a & (*p))"},
+
+		// The operand of a prefix operator includes field accesses.
+		{"*p.f", "(*p.f)"},
+		{"&s.f", "(&s.f)"},
+		{"&mut s.f.g", "(&mut s.f.g)"},
+		{"-s.f * 2", "((-s.f) * 2)"},
+		{"(*p).f", "(*p).f"},
+		{"**p", "(*(*p))"},
+		{"&a == null", "((&a) == null)"},
+
+		// Binary operators are left associative at every level.
+		{"a - b - c", "((a - b) - c)"},
+		{"a / b / c", "((a / b) / c)"},
+		{"a % b * c", "((a % b) * c)"},
+		{"a << b << c", "((a << b) << c)"},
+		{"a >> b << c", "((a >> b) << c)"},
+		{"a < b < c", "((a < b) < c)"},
+		{"a == b != c", "((a == b) != c)"},
+		{"a & b & c", "((a & b) & c)"},
+		{"a ^ b ^ c", "((a ^ b) ^ c)"},
+		{"a | b | c", "((a | b) | c)"},
+		{"a && b && c", "((a && b) && c)"},
+		{"a || b || c", "((a || b) || c)"},
+
+		// C precedence between levels.
+		{"a & 1 == 1", "(a & (1 == 1))"},
+		{"1 + 2 * 3 << 1", "((1 + (2 * 3)) << 1)"},
+		{"a << 1 + b", "(a << (1 + b))"},
+		{"a < b == c > d", "((a < b) == (c > d))"},
+		{"a | b ^ c & d", "(a | (b ^ (c & d)))"},
+		{"a && b || c && d", "((a && b) || (c && d))"},
+		{"a == b && c != d", "((a == b) && (c != d))"},
+		{"(a + b) * c", "((a + b) * c)"},
+		{"a * (b + c)", "(a * (b + c))"},
+
+		// Assignment is right associative and has the lowest precedence.
+		{"a = b", "(a = b)"},
+		{"a = b = c", "(a = (b = c))"},
+		{"a = b += c", "(a = (b += c))"},
+		{"a = b + c * d", "(a = (b + (c * d)))"},
+		{"a += b || c", "(a += (b || c))"},
+		{"*p = -v", "((*p) = (-v))"},
+		{"s.f <<= 2", "(s.f <<= 2)"},
+		{"a++", "(a++)"},
+		{"*p--", "((*p)--)"},
+		{"!true || false", "((!true) || false)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			if got := exprString(parseExpr(t, tt.src)); got != tt.want {
+				t.Errorf("%s: got %s, want %s", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+var binaryOps = []struct {
+	op   string
+	prec int
+}{
+	{"*", 10}, {"/", 10}, {"%", 10},
+	{"+", 9}, {"-", 9},
+	{"<<", 8}, {">>", 8},
+	{"<", 7}, {"<=", 7}, {">", 7}, {">=", 7},
+	{"==", 6}, {"!=", 6},
+	{"&", 5},
+	{"^", 4},
+	{"|", 3},
+	{"&&", 2},
+	{"||", 1},
+}
+
+// TestParse_BinaryPrecedence checks every pair of binary operators groups
+// following C precedence and left associativity.
+func TestParse_BinaryPrecedence(t *testing.T) {
+	for _, x := range binaryOps {
+		for _, y := range binaryOps {
+			src := fmt.Sprintf("a %s b %s c", x.op, y.op)
+			want := fmt.Sprintf("((a %s b) %s c)", x.op, y.op)
+			if y.prec > x.prec {
+				want = fmt.Sprintf("(a %s (b %s c))", x.op, y.op)
+			}
+			if got := exprString(parseExpr(t, src)); got != want {
+				t.Errorf("%s: got %s, want %s", src, got, want)
+			}
+
+			// Prefix operators bind tighter than either operator.
+			src = fmt.Sprintf("-a %s !b %s ~c", x.op, y.op)
+			want = fmt.Sprintf("(((-a) %s (!b)) %s (~c))", x.op, y.op)
+			if y.prec > x.prec {
+				want = fmt.Sprintf("((-a) %s ((!b) %s (~c)))", x.op, y.op)
+			}
+			if got := exprString(parseExpr(t, src)); got != want {
+				t.Errorf("%s: got %s, want %s", src, got, want)
+			}
+		}
+	}
+}
+
+func TestParse_AssignNotAllowed(t *testing.T) {
+	tests := []string{
+		"if (a = 1) {}",
+		"if (a += 1) {}",
+		"let b = (a = 2);",
+		"let b = a = 2;",
+		"return a = 1;",
+		"f(a = 1);",
+		"loop (a = b) {}",
+		"for i in 0..n = 1 {}",
+	}
+	for _, src := range tests {
+		t.Run(src, func(t *testing.T) {
+			_, err := parseFile(src)
+			var list diag.List
+			if !errors.As(err, &list) || len(list) == 0 {
+				t.Fatalf("%s: got err %v, want syntax error", src, err)
+			}
+			if list[0].Message != "assignment is only allowed as a statement" {
+				t.Errorf("%s: got error %q", src, list[0].Message)
+			}
+			if list[0].Found == "" || list[0].Expected == "" {
+				t.Errorf("%s: got expected %q found %q", src, list[0].Expected, list[0].Found)
+			}
+		})
+	}
+}
+
+func TestParse_ExpectedFound(t *testing.T) {
+	tests := []struct {
+		body     string
+		expected string
+		found    string
+	}{
+		{"return 1", "';'", "'}'"},
+		{"let x = ;", "expression", "';'"},
+		{"let = 1;", "'IDENT'", "'='"},
+		{"let true = 1;", "'IDENT'", "literal true"},
+		{"let x: i32 = 1 2;", "';'", "literal 2"},
+	}
+	for _, tt := range tests {
+		_, err := parseFile(tt.body)
+		var list diag.List
+		if !errors.As(err, &list) || len(list) != 1 {
+			t.Fatalf("%s: got err %v, want one syntax error", tt.body, err)
+		}
+		if list[0].Expected != tt.expected || list[0].Found != tt.found {
+			t.Errorf(
+				"%s: got expected %q found %q, want expected %q found %q",
+				tt.body, list[0].Expected, list[0].Found, tt.expected, tt.found,
+			)
+		}
+	}
+}
+
+func checkErrors(t *testing.T, err error, want []string) {
+	t.Helper()
+
+	var list diag.List
+	if !errors.As(err, &list) {
+		t.Fatalf("got error %v, want error list", err)
+	}
+	if len(list) != len(want) {
+		t.Fatalf("got errors:\n%s\nwant %d errors", list, len(want))
+	}
+	for i, err := range list {
+		if err.Error() != want[i] {
+			t.Errorf("error %d: got %q, want %q", i, err, want[i])
+		}
+	}
+}
+
+func checkDecls(t *testing.T, f *syntax.File, want []string) {
+	t.Helper()
+
+	var names []string
+	for _, decl := range f.Decls {
+		names = append(names, decl.(*syntax.FuncDecl).Name.Name)
+	}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("got decls %v, want %v", names, want)
+	}
+}
+
+// parseFile parses the statements as the body of a function.
+func parseFile(body string) (*syntax.File, error) {
+	src := "fn f() {\n" + body + "\n}\n"
+	return syntax.Parse(lex.NewScanner("test.nv", []byte(src)))
+}
+
+func parseExpr(t *testing.T, src string) syntax.Expr {
+	t.Helper()
+
+	f, err := parseFile(src + ";")
+	if err != nil {
+		t.Fatalf("%s: parse: %s", src, err)
+	}
+	body := f.Decls[0].(*syntax.FuncDecl).Body
+	if len(body.List) != 1 {
+		t.Fatalf("%s: got %d statements, want 1", src, len(body.List))
+	}
+	stmt, ok := body.List[0].(*syntax.ExprStmt)
+	if !ok {
+		t.Fatalf("%s: got %T, want expression statement", src, body.List[0])
+	}
+	return stmt.E
+}
+
+// exprString formats the expression with every unary, binary and
+// assignment expression parenthesized, to show the shape of the tree.
+func exprString(expr syntax.Expr) string {
+	switch expr := expr.(type) {
+	case *syntax.VarExpr:
+		return expr.Name.Name
+	case *syntax.BasicLitExpr:
+		return expr.Value
+	case *syntax.UnaryExpr:
+		if expr.Mut {
+			return "(&mut " + exprString(expr.Expr) + ")"
+		}
+		return "(" + expr.Op.String() + exprString(expr.Expr) + ")"
+	case *syntax.BinaryExpr:
+		return "(" + exprString(expr.L) + " " + expr.Op.String() + " " + exprString(expr.R) + ")"
+	case *syntax.AssignExpr:
+		if expr.Tok == lex.INC || expr.Tok == lex.DEC {
+			return "(" + exprString(expr.L) + expr.Tok.String() + ")"
+		}
+		return "(" + exprString(expr.L) + " " + expr.Tok.String() + " " + exprString(expr.R) + ")"
+	case *syntax.SelectorExpr:
+		return exprString(expr.X) + "." + expr.Sel.Name
+	case *syntax.CallExpr:
+		var args []string
+		for _, arg := range expr.Args {
+			args = append(args, exprString(arg))
+		}
+		name := expr.Func.Name
+		if expr.Recv != nil {
+			name = exprString(expr.Recv) + "." + name
+		} else if expr.Type != nil {
+			name = expr.Type.Name + "::" + name
+		}
+		return name + "(" + strings.Join(args, ", ") + ")"
+	default:
+		return fmt.Sprintf("%T", expr)
+	}
+}
@@ -1,34 +1,46 @@
 package syntax
 
 type Stmt interface {
+	Node
 	stmt()
 }
 
 type BlockStmt struct {
+	Span
+
 	List []Stmt
 }
 
 func (n *BlockStmt) stmt() {}
 
 type ReturnStmt struct {
+	Span
+
+	// Result is nil for a bare 'return;'.
 	Result Expr
 }
 
 func (n *ReturnStmt) stmt() {}
 
 type ExprStmt struct {
+	Span
+
 	E Expr
 }
 
 func (n *ExprStmt) stmt() {}
 
 type DeclStmt struct {
+	Span
+
 	Decl Decl
 }
 
 func (n *DeclStmt) stmt() {}
 
 type IfStmt struct {
+	Span
+
 	Cond Expr
 	Then Stmt
 	Else Stmt
@@ -37,22 +49,67 @@ type IfStmt struct {
 func (n *IfStmt) stmt() {}
 
 type LoopStmt struct {
+	Span
+
+	// Cond is nil if omitted, which loops forever.
 	Cond Expr
 	Body *BlockStmt
 
-	Label string
+	// Label is nil if the loop isn't labeled.
+	Label *Ident
 }
 
 func (n *LoopStmt) stmt() {}
 
+// ForStmt is a C style for loop, such as
+// 'for (let mut i: i32 = 0; i < n; i = i + 1) {}'.
+type ForStmt struct {
+	Span
+
+	// Init is a [DeclStmt] or [ExprStmt], or nil if omitted.
+	Init Stmt
+	// Cond is nil if omitted, which loops forever.
+	Cond Expr
+	// Post is nil if omitted.
+	Post Expr
+	Body *BlockStmt
+
+	// Label is nil if the loop isn't labeled.
+	Label *Ident
+}
+
+func (n *ForStmt) stmt() {}
+
+// RangeStmt is a loop over the integers from Low up to, but not including,
+// High, such as 'for i in 0..n {}'.
+type RangeStmt struct {
+	Span
+
+	Var  *Ident
+	Low  Expr
+	High Expr
+	Body *BlockStmt
+
+	// Label is nil if the loop isn't labeled.
+	Label *Ident
+}
+
+func (n *RangeStmt) stmt() {}
+
 type BreakStmt struct {
-	Label string
+	Span
+
+	// Label is nil if the statement targets the innermost loop.
+	Label *Ident
 }
 
 func (n *BreakStmt) stmt() {}
 
 type ContinueStmt struct {
-	Label string
+	Span
+
+	// Label is nil if the statement targets the innermost loop.
+	Label *Ident
 }
 
 func (n *ContinueStmt) stmt() {}
@@ -0,0 +1,26 @@
+package syntax
+
+// Type is a type in a declaration, such as 'i32' or '*MyStruct'.
+type Type interface {
+	Node
+	typeNode()
+}
+
+// NamedType refers to a type by name, such as 'i32'.
+type NamedType struct {
+	Span
+
+	Name *Ident
+}
+
+func (n *NamedType) typeNode() {}
+
+// PointerType is a pointer type, such as '*i32' or '*mut i32'.
+type PointerType struct {
+	Span
+
+	Mut  bool
+	Elem Type
+}
+
+func (n *PointerType) typeNode() {}
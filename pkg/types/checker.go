@@ -1,144 +1,709 @@
 package types
 
 import (
-	"fmt"
-
 	"github.com/andydunstall/nova/pkg/assert"
+	"github.com/andydunstall/nova/pkg/cfg"
+	"github.com/andydunstall/nova/pkg/diag"
+	"github.com/andydunstall/nova/pkg/lex"
 	"github.com/andydunstall/nova/pkg/syntax"
 )
 
 // Check type checks the given file and returns the type info.
+//
+// The checker continues after errors, so all type errors in the file are
+// returned as a [diag.List], along with any warnings. If there are only
+// warnings, they're recorded in [Info.Warnings].
 func Check(file *syntax.File) (*Info, error) {
 	checker := newChecker()
-	if err := checker.checkFile(file); err != nil {
+	checker.checkFile(file)
+	if err := checker.diags.Err(); err != nil {
 		return nil, err
 	}
+	checker.info.Warnings = checker.diags
 	return checker.info, nil
 }
 
 type checker struct {
-	info *Info
+	info  *Info
+	diags diag.List
+
+	// scope is the current innermost scope.
+	scope *Scope
+	// fn is the signature of the function being checked.
+	fn *Func
+	// node is the control flow graph node being checked, such as a
+	// statement or loop condition.
+	node syntax.Node
+	// moves maps the control flow graph nodes of the function to the moves
+	// they contain, in evaluation order.
+	moves map[syntax.Node][]move
+	// loops contains the labels of the enclosing loops, innermost last,
+	// with nil for a loop without a label.
+	loops []*syntax.Ident
+	// labels contains the loop labels declared in the function.
+	labels map[string]*syntax.Ident
 }
 
 func newChecker() *checker {
+	info := newInfo()
+	info.FileScope = NewScope(Universe)
 	return &checker{
-		info: newInfo(),
+		info:  info,
+		scope: info.FileScope,
 	}
 }
 
-func (c *checker) checkFile(file *syntax.File) error {
+func (c *checker) checkFile(file *syntax.File) {
+	// Declare struct types before resolving their fields, so structs can
+	// refer to structs declared later in the file.
+	var structs []*syntax.StructDecl
+	for _, decl := range file.Decls {
+		if decl, ok := decl.(*syntax.StructDecl); ok {
+			c.declareStruct(decl)
+			structs = append(structs, decl)
+		}
+	}
+	for _, decl := range structs {
+		c.resolveStruct(decl)
+	}
+	layouts := make(map[*Struct]layoutState)
+	for _, decl := range structs {
+		c.layoutStruct(c.info.Defs[decl.Name].Type.(*Struct), layouts)
+	}
+
+	// Declare all function signatures before checking bodies, so functions
+	// can be called before they are declared.
 	for _, decl := range file.Decls {
-		if err := c.checkDecl(decl); err != nil {
-			return err
+		if decl, ok := decl.(*syntax.FuncDecl); ok {
+			c.declareFunc(decl)
 		}
 	}
-	return nil
+
+	for _, decl := range file.Decls {
+		if decl, ok := decl.(*syntax.VarDecl); ok {
+			// Variables can only be declared in functions.
+			c.errorf(decl.Name, "global variables are not supported").
+				WithLabel("declared at top level").
+				WithNote("declare %s inside a function", decl.Name.Name)
+			continue
+		}
+		c.checkDecl(decl)
+	}
+	c.checkMain(file)
+}
+
+// checkMain checks the file declares the program entrypoint, main, which
+// takes no parameters and either returns nothing or an integer exit code.
+func (c *checker) checkMain(file *syntax.File) {
+	obj := c.info.FileScope.Lookup("main")
+	if obj == nil || obj.Kind != FuncObj {
+		d := diag.Errorf(diag.Span{Start: lex.Position{Filename: file.Name}}, "missing main function").
+			WithNote("declare the program entrypoint as 'fn main()' or 'fn main() -> i32'")
+		c.diags.Add(d)
+		return
+	}
+
+	fn := obj.Type.(*Func)
+	if len(fn.Params) != 0 {
+		c.errorf(fn.Params[0].Ident, "main must have no parameters").
+			WithSecondary(diag.SpanOf(obj.Ident), "main declared here")
+	}
+	if fn.Return != nil && !isInteger(fn.Return) && !isInvalid(fn.Return) {
+		c.errorf(obj.Ident, "main must return an integer exit code or nothing, not %s", fn.Return).
+			WithNote("the value returned by main is the process exit code")
+	}
 }
 
 // Statements.
 
-func (c *checker) checkStmt(stmt syntax.Stmt) error {
+func (c *checker) checkStmt(stmt syntax.Stmt) {
 	switch stmt := stmt.(type) {
 	case *syntax.DeclStmt:
-		return c.checkDecl(stmt.Decl)
+		c.node = stmt
+		c.checkDecl(stmt.Decl)
 	case *syntax.ReturnStmt:
-		return c.checkReturnStmt(stmt)
+		c.node = stmt
+		c.checkReturnStmt(stmt)
 	case *syntax.ExprStmt:
-		// TODO(andydunstall)
-		return nil
+		c.node = stmt
+		typ := c.checkExpr(stmt.E)
+		c.defaultUntyped(stmt.E, typ)
 	case *syntax.BlockStmt:
-		return c.checkBlockStmt(stmt)
+		c.checkBlockStmt(stmt)
+	case *syntax.IfStmt:
+		c.checkIfStmt(stmt)
+	case *syntax.LoopStmt:
+		c.checkLoopStmt(stmt)
+	case *syntax.ForStmt:
+		c.checkForStmt(stmt)
+	case *syntax.RangeStmt:
+		c.checkRangeStmt(stmt)
+	case *syntax.BreakStmt:
+		c.checkTarget(stmt, "break", stmt.Label)
+	case *syntax.ContinueStmt:
+		c.checkTarget(stmt, "continue", stmt.Label)
 	default:
 		assert.Panicf("unsupported stmt type: %#v", stmt)
-		return nil // Unreachable.
 	}
 }
 
-func (c *checker) checkReturnStmt(stmt *syntax.ReturnStmt) error {
-	return nil
+func (c *checker) checkReturnStmt(stmt *syntax.ReturnStmt) {
+	if stmt.Result == nil {
+		if c.fn.Return != nil {
+			c.errorf(stmt, "missing return value").
+				WithLabel("expected a value of type " + c.fn.Return.String())
+		}
+		return
+	}
+
+	typ := c.checkExpr(stmt.Result)
+	if c.fn.Return == nil {
+		c.defaultUntyped(stmt.Result, typ)
+		c.errorf(stmt.Result, "too many return values").
+			WithLabel("function has no return type")
+		return
+	}
+	c.assignable(stmt.Result, typ, c.fn.Return, "return statement")
+	c.consume(stmt.Result)
+}
+
+func (c *checker) checkBlockStmt(stmt *syntax.BlockStmt) {
+	c.openScope(stmt)
+	defer c.closeScope()
+
+	c.checkStmtList(stmt.List)
+}
+
+func (c *checker) checkStmtList(list []syntax.Stmt) {
+	for _, stmt := range list {
+		c.checkStmt(stmt)
+	}
+}
+
+func (c *checker) checkIfStmt(stmt *syntax.IfStmt) {
+	c.checkCond(stmt.Cond, "if statement")
+	c.checkScopedStmt(stmt.Then)
+	if stmt.Else != nil {
+		c.checkScopedStmt(stmt.Else)
+	}
+}
+
+func (c *checker) checkLoopStmt(stmt *syntax.LoopStmt) {
+	if stmt.Cond != nil {
+		c.checkCond(stmt.Cond, "loop statement")
+	}
+	c.checkLoop(stmt.Label, stmt.Body, nil)
+}
+
+func (c *checker) checkForStmt(stmt *syntax.ForStmt) {
+	// Variables declared by the init statement are scoped to the loop.
+	c.openScope(stmt)
+	defer c.closeScope()
+
+	if stmt.Init != nil {
+		c.checkStmt(stmt.Init)
+	}
+	if stmt.Cond != nil {
+		c.checkCond(stmt.Cond, "for statement")
+	}
+	c.checkLoop(stmt.Label, stmt.Body, stmt.Post)
+}
+
+func (c *checker) checkRangeStmt(stmt *syntax.RangeStmt) {
+	typ := c.checkRangeBounds(stmt)
+
+	c.openScope(stmt)
+	defer c.closeScope()
+
+	c.declare(&Object{
+		Kind:  Var,
+		Name:  stmt.Var.Name,
+		Type:  typ,
+		Ident: stmt.Var,
+	})
+	c.checkLoop(stmt.Label, stmt.Body, nil)
 }
 
-func (c *checker) checkBlockStmt(stmt *syntax.BlockStmt) error {
-	for _, stmt := range stmt.List {
-		if err := c.checkStmt(stmt); err != nil {
-			return err
+// checkRangeBounds checks the bounds of a range loop are integers of the
+// same type, and returns that type. Untyped bounds default to i32.
+func (c *checker) checkRangeBounds(stmt *syntax.RangeStmt) Type {
+	c.node = stmt.Low
+	lt := c.checkExpr(stmt.Low)
+	c.node = stmt.High
+	ht := c.checkExpr(stmt.High)
+	for _, bound := range []syntax.Expr{stmt.Low, stmt.High} {
+		typ := c.info.Types[bound]
+		if typ == nil {
+			c.errorNoValue(bound)
+			return Invalid
+		}
+		if isInvalid(typ) {
+			return Invalid
+		}
+		if !isInteger(typ) {
+			c.defaultUntyped(bound, typ)
+			c.errorf(bound, "cannot range over %s", typ).
+				WithLabel("expected an integer")
+			return Invalid
 		}
 	}
 
-	return nil
+	switch {
+	case lt == UntypedInt && ht == UntypedInt:
+		c.defaultUntyped(stmt.Low, lt)
+		c.defaultUntyped(stmt.High, ht)
+		return I32
+	case lt == UntypedInt:
+		c.convertUntyped(stmt.Low, ht)
+		return ht
+	case ht == UntypedInt:
+		c.convertUntyped(stmt.High, lt)
+		return lt
+	case Identical(lt, ht):
+		return lt
+	}
+	c.errorf(stmt.Low, "mismatched range bounds %s and %s", lt, ht).
+		WithLabel("has type "+lt.String()).
+		WithSecondary(diag.SpanOf(stmt.High), "has type "+ht.String())
+	return Invalid
+}
+
+// checkLoop checks the body of a loop and the post expression, if any,
+// which is evaluated at the end of each iteration.
+func (c *checker) checkLoop(label *syntax.Ident, body *syntax.BlockStmt, post syntax.Expr) {
+	if label != nil {
+		c.declareLabel(label)
+	}
+
+	c.loops = append(c.loops, label)
+	c.checkBlockStmt(body)
+	c.loops = c.loops[:len(c.loops)-1]
+
+	if post != nil {
+		c.node = post
+		typ := c.checkExpr(post)
+		c.defaultUntyped(post, typ)
+	}
+}
+
+// declareLabel declares a loop label, which must be unique within the
+// function.
+func (c *checker) declareLabel(label *syntax.Ident) {
+	if prev, ok := c.labels[label.Name]; ok {
+		c.errorf(label, "label %s already defined", label.Name).
+			WithLabel("redefined here").
+			WithSecondary(diag.SpanOf(prev), "previous definition")
+		return
+	}
+	c.labels[label.Name] = label
+}
+
+// checkTarget checks a break or continue statement targets an enclosing
+// loop, which is the loop with the given label, or the innermost loop if
+// the label is nil.
+func (c *checker) checkTarget(stmt syntax.Stmt, keyword string, label *syntax.Ident) {
+	if label == nil {
+		if len(c.loops) == 0 {
+			c.errorf(stmt, "%s is not in a loop", keyword).
+				WithLabel(keyword + " outside of a loop")
+		}
+		return
+	}
+
+	for i := len(c.loops) - 1; i >= 0; i-- {
+		if l := c.loops[i]; l != nil && l.Name == label.Name {
+			return
+		}
+	}
+	if def, ok := c.labels[label.Name]; ok {
+		c.errorf(label, "invalid %s label %s", keyword, label.Name).
+			WithLabel("not in the loop labeled "+label.Name).
+			WithSecondary(diag.SpanOf(def), "label defined here")
+		return
+	}
+	c.errorf(label, "label %s not defined", label.Name).
+		WithLabel("undefined label")
+}
+
+// checkScopedStmt checks the statement in its own scope, so declarations in
+// a branch without braces don't leak into the enclosing block.
+func (c *checker) checkScopedStmt(stmt syntax.Stmt) {
+	if block, ok := stmt.(*syntax.BlockStmt); ok {
+		c.checkBlockStmt(block)
+		return
+	}
+
+	c.openScope(stmt)
+	defer c.closeScope()
+
+	c.checkStmt(stmt)
+}
+
+func (c *checker) checkCond(cond syntax.Expr, context string) {
+	c.node = cond
+	typ := c.checkExpr(cond)
+	if typ == nil {
+		c.errorNoValue(cond)
+	} else if !isInvalid(typ) && !isBool(typ) {
+		c.defaultUntyped(cond, typ)
+		c.errorf(cond, "non-boolean condition in %s", context).
+			WithLabel("expected bool, found " + typeString(typ))
+	}
 }
 
 // Declarations.
 
-func (c *checker) checkDecl(decl syntax.Decl) error {
+func (c *checker) checkDecl(decl syntax.Decl) {
 	switch decl := decl.(type) {
 	case *syntax.VarDecl:
-		return c.checkVarDec(decl)
+		c.checkVarDec(decl)
 	case *syntax.FuncDecl:
-		return c.checkFuncDec(decl)
+		c.checkFuncDec(decl)
+	case *syntax.StructDecl:
+		// Checked by checkFile.
 	default:
 		assert.Panicf("unsupported decl type: %#v", decl)
-		return nil // Unreachable.
 	}
 }
 
-func (c *checker) checkVarDec(decl *syntax.VarDecl) error {
-	// TODO(andydunstall): Check conflicts.
+func (c *checker) checkVarDec(decl *syntax.VarDecl) {
+	// Check the initializer before declaring the variable, so it can't
+	// refer to itself.
+	var typ Type
+	if decl.Type != nil {
+		typ = c.resolveType(decl.Type)
+		exprType := c.checkExpr(decl.Expr)
+		c.assignable(decl.Expr, exprType, typ, "variable declaration")
+	} else {
+		typ = c.inferType(decl)
+	}
+	c.consume(decl.Expr)
 
-	p, ok := primatives[decl.Type]
-	if !ok {
-		return fmt.Errorf("unknown type: %s", decl.Type)
+	obj := &Object{
+		Kind:  Var,
+		Name:  decl.Name.Name,
+		Type:  typ,
+		Mut:   decl.Mut,
+		Ident: decl.Name,
 	}
+	c.declare(obj)
+	// A variable declared in a loop is initialised again by each
+	// iteration.
+	c.recordMove(moveInit, obj, nil)
+}
 
-	c.info.Defs[decl.Name] = &Object{
-		Name: decl.Name.Name,
-		Type: p,
+// inferType returns the type of a variable declared without a type, which
+// is the type of its initializer. Untyped integer constants default to i32.
+func (c *checker) inferType(decl *syntax.VarDecl) Type {
+	typ := c.checkExpr(decl.Expr)
+	switch {
+	case typ == nil:
+		c.errorNoValue(decl.Expr)
+		return Invalid
+	case typ == UntypedNull:
+		c.errorf(decl.Expr, "cannot infer the type of %s from null", decl.Name.Name).
+			WithLabel("type of null is unknown").
+			WithNote("add a pointer type, such as 'let %s: *T = null;'", decl.Name.Name)
+		return Invalid
 	}
+	return c.defaultUntyped(decl.Expr, typ)
+}
 
-	return nil
+// declareStruct declares the struct type. The fields are resolved by
+// resolveStruct once all types are declared.
+func (c *checker) declareStruct(decl *syntax.StructDecl) {
+	c.declare(&Object{
+		Kind:  TypeName,
+		Name:  decl.Name.Name,
+		Type:  &Struct{Name: decl.Name.Name},
+		Ident: decl.Name,
+	})
 }
 
-func (c *checker) checkFuncDec(decl *syntax.FuncDecl) error {
-	// TODO(andydunstall): Check conflicts.
+func (c *checker) resolveStruct(decl *syntax.StructDecl) {
+	typ := c.info.Defs[decl.Name].Type.(*Struct)
+
+	seen := make(map[string]*syntax.Field)
+	for _, field := range decl.Fields {
+		if prev, ok := seen[field.Name.Name]; ok {
+			c.errorf(field.Name, "duplicate field %s in struct %s", field.Name.Name, typ.Name).
+				WithLabel("duplicate field").
+				WithSecondary(diag.SpanOf(prev.Name), "previous declaration")
+			continue
+		}
+		seen[field.Name.Name] = field
 
-	// TODO(andydunstall)
+		typ.Fields = append(typ.Fields, &Field{
+			Name: field.Name.Name,
+			Type: c.resolveType(field.Type),
+		})
+	}
+}
 
+type layoutState int
+
+const (
+	layoutPending layoutState = iota
+	layoutActive
+	layoutDone
+)
+
+// layoutStruct computes the layout of the struct after the layout of any
+// struct fields, reporting an error if the struct contains itself.
+func (c *checker) layoutStruct(typ *Struct, states map[*Struct]layoutState) {
+	switch states[typ] {
+	case layoutDone:
+		return
+	case layoutActive:
+		obj := c.scope.Lookup(typ.Name)
+		c.errorf(obj.Ident, "invalid recursive type %s", typ.Name).
+			WithLabel(typ.Name + " contains itself")
+		return
+	}
+
+	states[typ] = layoutActive
+	for _, field := range typ.Fields {
+		if s, ok := field.Type.(*Struct); ok {
+			c.layoutStruct(s, states)
+			// Break the cycle so the layout is still computed.
+			if states[s] != layoutDone {
+				field.Type = Invalid
+			}
+		}
+	}
+	typ.layout()
+	states[typ] = layoutDone
+}
+
+// declareFunc declares the signature of the function.
+//
+// The parameters are declared in the function scope when checking the
+// body.
+func (c *checker) declareFunc(decl *syntax.FuncDecl) {
 	var params []*Object
 	for _, param := range decl.Params {
-		p, ok := primatives[param.Type]
-		if !ok {
-			return fmt.Errorf("unknown type: %s", param.Type)
-		}
+		params = append(params, &Object{
+			Kind:  Var,
+			Name:  param.Name.Name,
+			Type:  c.resolveType(param.Type),
+			Mut:   param.Mut,
+			Ident: param.Name,
+		})
+	}
 
-		o := &Object{
-			Name: param.Name.Name,
-			Type: p,
+	var ret Type
+	if decl.ReturnType != nil {
+		ret = c.resolveType(decl.ReturnType)
+	}
+
+	obj := &Object{
+		Kind: FuncObj,
+		Name: decl.Name.Name,
+		Type: &Func{
+			Params: params,
+			Return: ret,
+		},
+		Ident: decl.Name,
+	}
+	if decl.Recv != nil {
+		c.declareMethod(decl, obj)
+		return
+	}
+	if decl.Self != nil {
+		c.errorf(decl.Self, "self parameter is only allowed in methods").
+			WithLabel("not a method")
+	}
+	c.declare(obj)
+}
+
+// declareMethod adds the member function to the method set of the
+// receiver type.
+//
+// Functions named 'new' are constructors, which are called as
+// 'MyStruct::new()'. Any other member function is a method with an
+// implicit 'self' parameter referring to the receiver. The receiver is
+// immutable unless declared as 'mut self', except in the destructor.
+func (c *checker) declareMethod(decl *syntax.FuncDecl, obj *Object) {
+	c.info.Defs[obj.Ident] = obj
+
+	recv := decl.Recv
+	if decl.Self != nil && obj.Name == "new" {
+		c.errorf(decl.Self, "self parameter is not allowed in a constructor").
+			WithLabel("constructors have no receiver")
+	}
+
+	typ := c.lookupType(recv)
+	if isInvalid(typ) {
+		return
+	}
+	s, ok := typ.(*Struct)
+	if !ok {
+		c.errorf(recv, "cannot declare member function on %s", typ).
+			WithLabel("not a struct")
+		return
+	}
+
+	fn := obj.Type.(*Func)
+	if obj.Name != "new" {
+		fn.Recv = &Object{
+			Kind: Var,
+			Name: "self",
+			Type: s,
+			Mut:  obj.Name == "delete" || (decl.Self != nil && decl.Self.Mut),
 		}
-		c.info.Defs[param.Name] = o
+	}
+	if obj.Name == "delete" && (len(fn.Params) != 0 || fn.Return != nil) {
+		c.errorf(obj.Ident, "destructor %s::delete must have no parameters or return type", s).
+			WithLabel("invalid destructor")
+	}
 
-		params = append(params, o)
+	if prev := s.Method(obj.Name); prev != nil {
+		c.errorf(obj.Ident, "%s::%s redeclared", s, obj.Name).
+			WithLabel(obj.Name+" redeclared here").
+			WithSecondary(diag.SpanOf(prev.Ident), "previous declaration")
+		return
 	}
+	if s.Field(obj.Name) != nil {
+		c.errorf(obj.Ident, "%s has both a field and a method named %s", s, obj.Name).
+			WithLabel("conflicts with field")
+		return
+	}
+	s.Methods = append(s.Methods, obj)
+}
 
-	var ret Type
-	if decl.ReturnType != "" {
-		var ok bool
-		ret, ok = primatives[decl.ReturnType]
-		if !ok {
-			return fmt.Errorf("unknown type: %s", decl.ReturnType)
+func (c *checker) checkFuncDec(decl *syntax.FuncDecl) {
+	fn := c.info.Defs[decl.Name].Type.(*Func)
+
+	c.fn = fn
+	c.moves = make(map[syntax.Node][]move)
+	c.labels = make(map[string]*syntax.Ident)
+	defer func() {
+		c.fn = nil
+		c.node = nil
+		c.moves = nil
+		c.labels = nil
+	}()
+
+	// The parameters and top level of the body share a scope, so the body
+	// can't redeclare a parameter.
+	c.openScope(decl)
+	defer c.closeScope()
+
+	if fn.Recv != nil {
+		c.declare(fn.Recv)
+	}
+	for _, param := range fn.Params {
+		c.declare(param)
+	}
+
+	c.checkStmtList(decl.Body.List)
+	c.checkControlFlow(decl, fn)
+}
+
+// checkControlFlow reports functions with a return type that may end
+// without returning and uses of moved variables, and warns about
+// unreachable statements.
+func (c *checker) checkControlFlow(decl *syntax.FuncDecl, fn *Func) {
+	g := cfg.New(decl.Body)
+	c.info.CFGs[decl] = g
+	c.checkMoves(g)
+
+	if fn.Return != nil && g.Exit.Live {
+		// Report the closing brace of the body.
+		end := decl.Body.End()
+		start := end
+		start.Offset--
+		start.Column--
+		d := diag.Errorf(diag.Span{Start: start, End: end}, "missing return").
+			WithLabel("function may end without returning a value")
+		if decl.ReturnType != nil {
+			d.WithSecondary(diag.SpanOf(decl.ReturnType), "function returns "+fn.Return.String())
 		}
+		c.diags.Add(d)
 	}
 
-	fn := &Func{
-		Params: params,
-		Return: ret,
+	for _, stmt := range g.Unreachable() {
+		c.warnf(stmt, "unreachable code").
+			WithLabel("unreachable statement")
 	}
-	c.info.Defs[decl.Name] = &Object{
-		Name: decl.Name.Name,
-		Type: fn,
+}
+
+// Scopes.
+
+// openScope opens a new scope nested in the current scope, for the given
+// node.
+func (c *checker) openScope(node syntax.Node) {
+	c.scope = NewScope(c.scope)
+	c.info.Scopes[node] = c.scope
+}
+
+func (c *checker) closeScope() {
+	c.scope = c.scope.Parent()
+}
+
+// declare declares the object in the current scope and records its
+// definition, reporting an error if the name is already declared in the
+// scope.
+func (c *checker) declare(obj *Object) {
+	if obj.Ident != nil {
+		c.info.Defs[obj.Ident] = obj
+	}
+
+	if existing := c.scope.Insert(obj); existing != nil {
+		d := c.errorf(obj.Ident, "%s redeclared in this block", obj.Name).
+			WithLabel(obj.Name + " redeclared here")
+		if existing.Ident != nil {
+			d.WithSecondary(diag.SpanOf(existing.Ident), "previous declaration")
+		}
+	}
+}
+
+// resolveType resolves the type expression, reporting an error if it
+// doesn't refer to a type.
+func (c *checker) resolveType(typ syntax.Type) Type {
+	switch typ := typ.(type) {
+	case *syntax.NamedType:
+		return c.lookupType(typ.Name)
+	case *syntax.PointerType:
+		elem := c.resolveType(typ.Elem)
+		if isInvalid(elem) {
+			return Invalid
+		}
+		return &Pointer{Mut: typ.Mut, Elem: elem}
+	default:
+		assert.Panicf("unsupported type expression: %#v", typ)
+		return nil // Unreachable.
 	}
+}
 
-	if err := c.checkBlockStmt(decl.Body); err != nil {
-		return err
+// lookupType resolves the named type, reporting an error if the name
+// doesn't refer to a type.
+func (c *checker) lookupType(name *syntax.Ident) Type {
+	_, obj := c.scope.LookupParent(name.Name)
+	if obj == nil {
+		c.errorf(name, "unknown type: %s", name.Name)
+		return Invalid
 	}
-	return nil
+	if obj.Kind != TypeName {
+		c.errorf(name, "%s is not a type", name.Name).
+			WithLabel(name.Name + " is a " + obj.Kind.String())
+		return Invalid
+	}
+	c.info.Uses[name] = obj
+	return obj.Type
+}
+
+// Errors.
+
+// errorf records an error diagnostic at the node.
+func (c *checker) errorf(node diag.Node, format string, a ...any) *diag.Diagnostic {
+	d := diag.Errorf(diag.SpanOf(node), format, a...)
+	c.diags.Add(d)
+	return d
+}
+
+func (c *checker) warnf(node diag.Node, format string, a ...any) *diag.Diagnostic {
+	d := diag.Warningf(diag.SpanOf(node), format, a...)
+	c.diags.Add(d)
+	return d
 }
@@ -0,0 +1,995 @@
+package types
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/andydunstall/nova/pkg/diag"
+	"github.com/andydunstall/nova/pkg/lex"
+	"github.com/andydunstall/nova/pkg/syntax"
+)
+
+func TestCheck_Main(t *testing.T) {
+	tests := []struct {
+		src  string
+		errs []string
+	}{
+		{"fn main() {}", nil},
+		{"fn main() -> i32 { return 0; }", nil},
+		{"fn main() -> u8 { return 0; }", nil},
+		{"fn f() {}", []string{"test.nv: missing main function"}},
+		{"fn main(a: i32) {}", []string{"test.nv:1:9: main must have no parameters"}},
+		{
+			"fn main() -> bool { return 1 == 1; }",
+			[]string{"test.nv:1:4: main must return an integer exit code or nothing, not bool"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			checkErrors(t, checkDiagnostics(t, tt.src), tt.errs)
+		})
+	}
+}
+
+func TestCheck_GlobalVariable(t *testing.T) {
+	list := checkDiagnostics(t, "let x: i32 = 1;\n\nfn main() {}\n")
+	checkErrors(t, list, []string{"test.nv:1:5: global variables are not supported"})
+}
+
+func TestCheck_Errors(t *testing.T) {
+	// The checker continues after an error, so reports all errors in the
+	// file.
+	src := `fn f(a: foo) -> bar {
+	let x: baz = 1;
+	return 1;
+}
+
+fn main() {}
+`
+	checkErrors(t, checkDiagnostics(t, src), []string{
+		"test.nv:1:9: unknown type: foo",
+		"test.nv:1:17: unknown type: bar",
+		"test.nv:2:9: unknown type: baz",
+	})
+}
+
+// checkDiagnostics checks the source and returns the reported diagnostics,
+// including warnings.
+func checkDiagnostics(t *testing.T, src string) diag.List {
+	t.Helper()
+
+	file, err := syntax.Parse(lex.NewScanner("test.nv", []byte(src)))
+	if err != nil {
+		t.Fatalf("parse: %s", err)
+	}
+	info, err := Check(file)
+	if err == nil {
+		return info.Warnings
+	}
+	var list diag.List
+	if !errors.As(err, &list) {
+		t.Fatalf("check: %s", err)
+	}
+	return list
+}
+
+// checkErrors checks the diagnostics formatted as 'file:line:column:
+// message' match want.
+func checkErrors(t *testing.T, list diag.List, want []string) {
+	t.Helper()
+
+	if len(list) != len(want) {
+		t.Fatalf("got diagnostics:\n%s\nwant %d", list, len(want))
+	}
+	for i, d := range list {
+		if d.Error() != want[i] {
+			t.Errorf("diagnostic %d: got %q, want %q", i, d.Error(), want[i])
+		}
+	}
+}
+
+func TestCheck_Expr(t *testing.T) {
+	tests := []struct {
+		body string
+		errs []string
+	}{
+		{"let x: i32 = 1 + 2 * 3;", nil},
+		{"let x: i64 = g(1, 2) - 1;", nil},
+		{"let x: u8 = 1;\n\tlet y: i64 = 2;\n\tlet z: i64 = x + y;", []string{"mismatched types u8 and i64"}},
+		{"let x: bool = 1;", []string{"cannot use integer constant as bool value in variable declaration"}},
+		{"let x: i32 = y;", []string{"undefined: y"}},
+		{"let x: i64 = g(1);", []string{"not enough arguments in call to g"}},
+		{"let x: i64 = g(1, 2, 3);", []string{"too many arguments in call to g"}},
+		{"let x: i32 = noop();", []string{"expression has no value"}},
+		{"let x: i32 = g;", []string{"cannot use function g as a value"}},
+		{"let x: i32 = 1;\n\tx(2);", []string{"cannot call non-function x"}},
+		{"let x: i32 = -(1 == 1);", []string{"operator - not defined on bool"}},
+		{"let x: i32 = 1;\n\tlet b: bool = !x;", []string{"operator ! not defined on i32"}},
+		{"let b: bool = (1 == 1) < (2 == 2);", []string{"operator < not defined on bool"}},
+		{"let b: bool = 1 && (1 == 1);", []string{"operator && not defined on untyped int"}},
+		{"if (1) {}", []string{"non-boolean condition in if statement"}},
+		{"return 1;", []string{"too many return values"}},
+		{"let x: u8 = u8(g(1, 2));", nil},
+		{"let x: i32 = i32(1, 2);", []string{"conversion to i32 requires exactly one argument"}},
+		{"let b: bool = bool(1);", nil},
+		{"let x: u8 = g(1, 2);", []string{"cannot use value of type i64 as u8 value in variable declaration"}},
+		{"g(1, 2) = 2;", []string{"cannot assign to expression"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			src := `fn g(a: i32, b: u8) -> i64 {
+	return 0;
+}
+
+fn noop() {}
+
+fn main() {
+	` + tt.body + `
+}
+`
+			checkMessages(t, checkDiagnostics(t, src), tt.errs)
+		})
+	}
+}
+
+// checkMessages checks the messages of the diagnostics match want.
+func checkMessages(t *testing.T, list diag.List, want []string) {
+	t.Helper()
+
+	if len(list) != len(want) {
+		t.Fatalf("got diagnostics:\n%s\nwant %q", list, want)
+	}
+	for i, d := range list {
+		if d.Message != want[i] {
+			t.Errorf("diagnostic %d: got %q, want %q", i, d.Message, want[i])
+		}
+	}
+}
+
+func TestCheck_ExprTypes(t *testing.T) {
+	tests := []struct {
+		expr string
+		typ  string
+		want []Type
+	}{
+		// Constants are converted to the type of their context.
+		{"1 + 2", "u8", []Type{U8, U8, U8}},
+		{"x + 1", "i64", []Type{I64, I64, I64}},
+		// Constants without a context have the default type.
+		{"1 < 2", "bool", []Type{Bool, I32, I32}},
+		{"x < 2", "bool", []Type{Bool, I64, I64}},
+		// A shift count has its own type, and a constant shifted by a
+		// non-constant count has the type of its context.
+		{"x << 1", "i64", []Type{I64, I64, U64}},
+		{"1 << 2", "u8", []Type{U8, U8, U64}},
+		{"1 << x", "u64", []Type{U64, U64, I64}},
+		{"(1 << x) + 1", "u16", []Type{U16, U16, U16}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			src := "fn main() {\n\tlet x: i64 = 0;\n\tlet y: " + tt.typ + " = " + tt.expr + ";\n}\n"
+			file, err := syntax.Parse(lex.NewScanner("test.nv", []byte(src)))
+			if err != nil {
+				t.Fatalf("parse: %s", err)
+			}
+			info, err := Check(file)
+			if err != nil {
+				t.Fatalf("check: %s", err)
+			}
+
+			body := file.Decls[0].(*syntax.FuncDecl).Body
+			expr := body.List[1].(*syntax.DeclStmt).Decl.(*syntax.VarDecl).Expr.(*syntax.BinaryExpr)
+			got := []Type{info.Types[expr], info.Types[expr.L], info.Types[expr.R]}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got types %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestCheck_Scopes(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		errs []string
+	}{
+		{
+			"redeclared",
+			"fn main() {\n\tlet x: i32 = 1;\n\tlet x: i32 = 2;\n}\n",
+			[]string{"x redeclared in this block"},
+		},
+		{
+			"shadowed in block",
+			"fn main() {\n\tlet x: i32 = 1;\n\t{\n\t\tlet x: u8 = 2;\n\t\tlet y: u8 = x;\n\t}\n}\n",
+			nil,
+		},
+		{
+			"out of scope",
+			"fn main() {\n\t{\n\t\tlet y: i32 = 1;\n\t}\n\tlet z: i32 = y;\n}\n",
+			[]string{"undefined: y"},
+		},
+		{
+			"branch scope",
+			"fn main() {\n\tif (1 == 1) let y: i32 = 1;\n\tlet z: i32 = y;\n}\n",
+			[]string{"undefined: y"},
+		},
+		{
+			"parameter redeclared",
+			"fn f(a: i32) {\n\tlet a: i32 = 1;\n}\n\nfn main() {}\n",
+			[]string{"a redeclared in this block"},
+		},
+		{
+			"function redeclared",
+			"fn f() {}\n\nfn f() {}\n\nfn main() {}\n",
+			[]string{"f redeclared in this block"},
+		},
+		{
+			"call before declaration",
+			"fn main() -> i32 {\n\treturn f();\n}\n\nfn f() -> i32 {\n\treturn 1;\n}\n",
+			nil,
+		},
+		{
+			"variable as type",
+			"fn main() {\n\tlet x: i32 = 1;\n\tlet y: x = 2;\n}\n",
+			[]string{"x is not a type"},
+		},
+		{
+			"type shadowed",
+			"fn main() {\n\tlet i32: u8 = 1;\n\tlet y: i32 = 2;\n}\n",
+			[]string{"i32 is not a type"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkMessages(t, checkDiagnostics(t, tt.src), tt.errs)
+		})
+	}
+}
+
+func TestCheck_Uses(t *testing.T) {
+	src := `fn main() -> i32 {
+	let x: i32 = 1;
+	{
+		let x: i32 = 2;
+		return x;
+	}
+	return x;
+}
+`
+	file, err := syntax.Parse(lex.NewScanner("test.nv", []byte(src)))
+	if err != nil {
+		t.Fatalf("parse: %s", err)
+	}
+	info, err := Check(file)
+	if err != nil {
+		t.Fatalf("check: %s", err)
+	}
+
+	fn := file.Decls[0].(*syntax.FuncDecl)
+	outer := fn.Body.List[0].(*syntax.DeclStmt).Decl.(*syntax.VarDecl)
+	block := fn.Body.List[1].(*syntax.BlockStmt)
+	inner := block.List[0].(*syntax.DeclStmt).Decl.(*syntax.VarDecl)
+
+	innerUse := block.List[1].(*syntax.ReturnStmt).Result.(*syntax.VarExpr).Name
+	if got := info.ObjectOf(innerUse); got == nil || got != info.Defs[inner.Name] {
+		t.Errorf("inner use: got %v, want inner x", got)
+	}
+	outerUse := fn.Body.List[2].(*syntax.ReturnStmt).Result.(*syntax.VarExpr).Name
+	if got := info.ObjectOf(outerUse); got == nil || got != info.Defs[outer.Name] {
+		t.Errorf("outer use: got %v, want outer x", got)
+	}
+
+	// The function scope contains x, and the block scope its own x.
+	scope := info.Scopes[fn]
+	if scope == nil || scope.Parent() != info.FileScope || scope.Lookup("x") != info.Defs[outer.Name] {
+		t.Errorf("unexpected function scope")
+	}
+	if inner := info.Scopes[block]; inner == nil || inner.Parent() != scope {
+		t.Errorf("unexpected block scope")
+	}
+	if info.FileScope.Parent() != Universe || info.FileScope.Lookup("main") == nil {
+		t.Errorf("unexpected file scope")
+	}
+}
+
+func TestCheck_ConstOverflow(t *testing.T) {
+	tests := []struct {
+		body string
+		errs []string
+	}{
+		{"let x: u8 = 0xff;", nil},
+		{"let x: u8 = 0x1_00;", []string{"constant 256 overflows u8"}},
+		{"let x: i8 = -0b1000_0000;", nil},
+		{"let x: i8 = 0o200;", []string{"constant 128 overflows i8"}},
+		{"let x: u16 = -1;", []string{"constant -1 overflows u16"}},
+		{"let x: u64 = 0xffff_ffff_ffff_ffff;", nil},
+		{"let x: i64 = 0xffff_ffff_ffff_ffff;", []string{"constant 18446744073709551615 overflows i64"}},
+		{"let x: i32 = ~0;", nil},
+		{"let x: u32 = ~0;", []string{"constant -1 overflows u32"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			src := "fn main() {\n\t" + tt.body + "\n}\n"
+			checkMessages(t, checkDiagnostics(t, src), tt.errs)
+		})
+	}
+}
+
+func TestCheck_Structs(t *testing.T) {
+	tests := []struct {
+		body string
+		errs []string
+	}{
+		{"let p: P = P{x: 1, y: 2};\n\tlet x: i32 = p.x;", nil},
+		{"let mut p: P = P{y: 2};\n\tp.x = 3;", nil},
+		{"let q: Q = Q{p: P{x: 1, y: 2}};\n\tlet y: u8 = q.p.y;", nil},
+		{"let p: P = P{z: 1};", []string{"unknown field z in struct literal of type P"}},
+		{"let p: P = P{x: 1, x: 2};", []string{"duplicate field x in struct literal"}},
+		{"let p: P = P{y: 256};", []string{"constant 256 overflows u8"}},
+		{"let p: P = P{x: 1};\n\tlet y: i32 = p.y;", []string{"cannot use value of type u8 as i32 value in variable declaration"}},
+		{"let p: P = P{x: 1};\n\tlet z: i32 = p.z;", []string{"P has no field z"}},
+		{"let x: i32 = 1;\n\tlet y: i32 = x.y;", []string{"i32 has no field y"}},
+		{"let p: P = i32{x: 1};", []string{"i32 is not a struct type"}},
+		{"let p: P = Q{};", []string{"cannot use value of type Q as P value in variable declaration"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			src := `struct P {
+	x i32
+	y u8
+};
+
+struct Q {
+	p P
+};
+
+fn main() {
+	` + tt.body + `
+}
+`
+			checkMessages(t, checkDiagnostics(t, src), tt.errs)
+		})
+	}
+}
+
+func TestCheck_StructDecls(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		errs []string
+	}{
+		{
+			"duplicate field",
+			"struct P {\n\tx i32\n\tx i32\n};\n\nfn main() {}\n",
+			[]string{"duplicate field x in struct P"},
+		},
+		{
+			"recursive",
+			"struct P {\n\tp P\n};\n\nfn main() {}\n",
+			[]string{"invalid recursive type P"},
+		},
+		{
+			"unknown field type",
+			"struct P {\n\tx foo\n};\n\nfn main() {}\n",
+			[]string{"unknown type: foo"},
+		},
+		{
+			"main returns struct",
+			"struct P {\n\tx i32\n};\n\nfn main() -> P {\n\treturn P{x: 1};\n}\n",
+			[]string{"main must return an integer exit code or nothing, not P"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkMessages(t, checkDiagnostics(t, tt.src), tt.errs)
+		})
+	}
+}
+
+func TestCheck_Methods(t *testing.T) {
+	tests := []struct {
+		body string
+		errs []string
+	}{
+		{"let mut c: C = C::new(1);\n\tc.inc();\n\tlet n: i32 = c.get();", nil},
+		{"let c: C = C::new(1);\n\tc.dec();", []string{"C has no method dec"}},
+		{"let x: i32 = 1;\n\tx.inc();", []string{"i32 has no method inc"}},
+		{"let c: C = C::new(1);\n\tc.new(1);", []string{"C::new has no receiver"}},
+		{"let c: C = C::make(1);", []string{"C has no member function make"}},
+		{"C::inc();", []string{"cannot call method C::inc without a receiver"}},
+		{"let c: C = C::new(1);\n\tlet f: i32 = c.get;", []string{"cannot use method C::get as a value"}},
+		{"let c: C = i32::new(1);", []string{"i32 is not a struct type"}},
+		{"let c: C = C::new();", []string{"not enough arguments in call to C::new"}},
+		{"let c: C = C::new(1);\n\tlet n: u8 = c.get();", []string{"cannot use value of type i32 as u8 value in variable declaration"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			src := `struct C {
+	n i32
+};
+
+fn C::new(n: i32) -> C {
+	return C{n: n};
+}
+
+fn C::inc(mut self) {
+	self.n = self.n + 1;
+}
+
+fn C::get() -> i32 {
+	return self.n;
+}
+
+fn main() {
+	` + tt.body + `
+}
+`
+			checkMessages(t, checkDiagnostics(t, src), tt.errs)
+		})
+	}
+}
+
+func TestCheck_MethodDecls(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		errs []string
+	}{
+		{
+			"not a struct",
+			"fn i32::f() {}\n\nfn main() {}\n",
+			[]string{"cannot declare member function on i32"},
+		},
+		{
+			"redeclared",
+			"struct C {\n\tn i32\n};\n\nfn C::f() {}\n\nfn C::f() {}\n\nfn main() {}\n",
+			[]string{"C::f redeclared"},
+		},
+		{
+			"field and method",
+			"struct C {\n\tn i32\n};\n\nfn C::n() {}\n\nfn main() {}\n",
+			[]string{"C has both a field and a method named n"},
+		},
+		{
+			"destructor signature",
+			"struct C {\n\tn i32\n};\n\nfn C::delete(n: i32) {}\n\nfn main() {}\n",
+			[]string{"destructor C::delete must have no parameters or return type"},
+		},
+		{
+			"method named main",
+			"struct C {\n\tn i32\n};\n\nfn C::main() {}\n",
+			[]string{"missing main function"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkMessages(t, checkDiagnostics(t, tt.src), tt.errs)
+		})
+	}
+}
+
+func TestCheck_Moves(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		errs []string
+	}{
+		{"use after move", "let a: R = R{n: 1};\n\tconsume(a);\n\tconsume(a);", []string{"use of moved value: a"}},
+		{"field after move", "let a: R = R{n: 1};\n\tlet b: R = a;\n\tlet n: i32 = a.n;", []string{"use of moved value: a"}},
+		{"method after move", "let a: R = R{n: 1};\n\tconsume(a);\n\ta.get();", []string{"use of moved value: a"}},
+		{"reported once", "let a: R = R{n: 1};\n\tconsume(a);\n\ta.get();\n\ta.get();", []string{"use of moved value: a"}},
+		{"reassigned", "let mut a: R = R{n: 1};\n\tconsume(a);\n\ta = R{n: 2};\n\tconsume(a);", nil},
+		{"copy without destructor", "let p: P = P{n: 1};\n\tlet q: P = p;\n\tlet r: P = p;", nil},
+		{"moved on one branch", "let a: R = R{n: 1};\n\tif (c) {\n\t\tconsume(a);\n\t}\n\tconsume(a);", []string{"use of moved value: a"}},
+		{"moved on both branches", "let a: R = R{n: 1};\n\tif (c) {\n\t\tconsume(a);\n\t} else {\n\t\tconsume(a);\n\t}", nil},
+		{"moved before return", "let a: R = R{n: 1};\n\tif (c) {\n\t\tconsume(a);\n\t\treturn 1;\n\t}\n\tconsume(a);", nil},
+		{"moved in loop", "let a: R = R{n: 1};\n\tloop (c) {\n\t\tconsume(a);\n\t}", []string{"use of moved value: a"}},
+		{"moved then break", "let a: R = R{n: 1};\n\tloop (c) {\n\t\tconsume(a);\n\t\tbreak;\n\t}", nil},
+		{"declared in loop", "loop (c) {\n\t\tlet a: R = R{n: 1};\n\t\tconsume(a);\n\t}", nil},
+		{"used after loop", "let a: R = R{n: 1};\n\tloop (c) {\n\t\tconsume(a);\n\t\tbreak;\n\t}\n\tconsume(a);", []string{"use of moved value: a"}},
+		{"moved later in loop", "let a: R = R{n: 1};\n\tloop (c) {\n\t\ta.get();\n\t\tconsume(a);\n\t}", []string{"use of moved value: a"}},
+		{"moved then continue", "let a: R = R{n: 1};\n\tloop (c) {\n\t\tif (c) {\n\t\t\tconsume(a);\n\t\t\tcontinue;\n\t\t}\n\t}", []string{"use of moved value: a"}},
+		{"reassigned in loop", "let mut a: R = R{n: 1};\n\tloop (c) {\n\t\tconsume(a);\n\t\ta = R{n: 2};\n\t}\n\tconsume(a);", nil},
+		{"moved in unreachable code", "return 1;\n\tlet a: R = R{n: 1};\n\tconsume(a);\n\tconsume(a);", []string{"unreachable code"}},
+		{"move field", "let h: H = H{r: R{n: 1}};\n\tconsume(h.r);", []string{"cannot move out of field r"}},
+		{"explicit destructor", "let mut a: R = R{n: 1};\n\ta.delete();", []string{"explicit destructor calls are not allowed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `struct R {
+	n i32
+};
+
+fn R::delete() {}
+
+fn R::get() -> i32 {
+	return self.n;
+}
+
+struct P {
+	n i32
+};
+
+struct H {
+	r R
+};
+
+fn consume(r: R) {}
+
+fn f(c: bool) -> i32 {
+	` + tt.body + `
+	return 0;
+}
+
+fn main() {}
+`
+			checkMessages(t, checkDiagnostics(t, src), tt.errs)
+		})
+	}
+
+	src := `struct R {
+	n i32
+};
+
+fn R::delete() {}
+
+fn R::take() {
+	consume(self);
+}
+
+fn consume(r: R) {}
+
+fn main() {}
+`
+	checkMessages(t, checkDiagnostics(t, src), []string{"cannot move out of self"})
+}
+
+func TestCheck_Pointers(t *testing.T) {
+	tests := []struct {
+		body string
+		errs []string
+	}{
+		{"let x: i32 = 1;\n\tlet p: *i32 = &x;\n\tlet y: i32 = *p + 1;", nil},
+		{"let mut x: i32 = 1;\n\tlet p: *mut i32 = &mut x;\n\t*p = 2;", nil},
+		{"let x: i32 = 1;\n\tlet p: *i32 = &x;\n\tlet pp: **i32 = &p;\n\tlet y: i32 = **pp;", nil},
+		{"let n: N = N{v: 1, next: null};\n\tlet p: *N = &n;\n\tlet v: i32 = p.next.v;", nil},
+		{"let n: N = N{v: 1, next: null};\n\tlet p: *N = &n;\n\tlet v: i32 = (*p).v;", nil},
+		{"let p: *i32 = null;\n\tlet b: bool = p == null;", nil},
+		{"let x: i32 = 1;\n\tlet y: i32 = *x;", []string{"cannot dereference i32"}},
+		{"let p: *i32 = null;\n\tlet y: i32 = p;", []string{"cannot use value of type *i32 as i32 value in variable declaration"}},
+		{"let x: u8 = 1;\n\tlet p: *i32 = &x;", []string{"cannot use value of type *u8 as *i32 value in variable declaration"}},
+		{"let x: i32 = null;", []string{"cannot use null as i32 value in variable declaration"}},
+		{"let p: *i32 = &1;", []string{"cannot take the address of a literal"}},
+		{"let p: *N = &N{v: 1, next: null};", []string{"cannot take the address of a struct literal"}},
+		{"let p: *i32 = &f();", []string{"cannot take the address of a call result"}},
+		{"let p: *foo = null;", []string{"unknown type: foo"}},
+		{"let r: R = R{n: 1};\n\tlet p: *R = &r;\n\tlet s: R = *p;", []string{"cannot move out of a pointer"}},
+		{"let r: R = R{n: 1};\n\tlet p: *R = &r;\n\tp.get();", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			src := `struct N {
+	v i32
+	next *N
+};
+
+struct R {
+	n i32
+};
+
+fn R::delete() {}
+
+fn R::get() -> i32 {
+	return self.n;
+}
+
+fn f() -> i32 {
+	return 1;
+}
+
+fn main() {
+	` + tt.body + `
+}
+`
+			checkMessages(t, checkDiagnostics(t, src), tt.errs)
+		})
+	}
+}
+
+func TestCheck_Mutability(t *testing.T) {
+	tests := []struct {
+		body string
+		errs []string
+	}{
+		{"let mut x: i32 = 1;\n\tx = 2;", nil},
+		{"let x: i32 = 1;\n\tx = 2;", []string{"cannot assign to immutable variable x"}},
+		{"a = 2;", []string{"cannot assign to immutable variable a"}},
+		{"b = 2;", nil},
+		{"let p: P = P{x: 1};\n\tp.x = 2;", []string{"cannot assign to immutable variable p"}},
+		{"let mut p: P = P{x: 1};\n\tp.x = 2;", nil},
+		{"let x: i32 = 1;\n\tlet p: *mut i32 = &mut x;", []string{"cannot take a mutable pointer to immutable variable x"}},
+		{"let mut x: i32 = 1;\n\tlet p: *i32 = &mut x;\n\t*p = 2;", []string{"cannot assign to a value behind an immutable pointer"}},
+		{"let mut x: i32 = 1;\n\tlet p: *i32 = &x;", nil},
+		{"let x: i32 = 1;\n\tlet p: *mut i32 = &x;", []string{"cannot use value of type *i32 as *mut i32 value in variable declaration"}},
+		{"let mut p: P = P{x: 1};\n\tlet q: *P = &p;\n\tq.x = 2;", []string{"cannot assign to a value behind an immutable pointer"}},
+		{"let mut p: P = P{x: 1};\n\tlet q: *mut P = &mut p;\n\tq.x = 2;\n\tq.set();", nil},
+		{"let p: P = P{x: 1};\n\tp.set();", []string{"cannot call mutating method set on immutable variable p"}},
+		{"let mut p: P = P{x: 1};\n\tlet q: *P = &p;\n\tq.set();\n\tlet x: i32 = q.get();", []string{"cannot call mutating method set on a value behind an immutable pointer"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			src := `struct P {
+	x i32
+};
+
+fn P::set(mut self) {
+	self.x = 1;
+}
+
+fn P::get() -> i32 {
+	return self.x;
+}
+
+fn f(a: i32, mut b: i32) {
+	` + tt.body + `
+}
+
+fn main() {}
+`
+			checkMessages(t, checkDiagnostics(t, src), tt.errs)
+		})
+	}
+}
+
+func TestCheck_MutableReceiver(t *testing.T) {
+	tests := []struct {
+		name string
+		decl string
+		errs []string
+	}{
+		{"immutable self", "fn P::f() {\n\tself.x = 1;\n}", []string{"cannot assign to immutable receiver self"}},
+		{"mutable self", "fn P::f(mut self) {\n\tself.x = 1;\n}", nil},
+		{"destructor", "fn P::delete() {\n\tself.x = 1;\n}", nil},
+		{"constructor", "fn P::new(mut self) -> P {\n\treturn P{x: 1};\n}", []string{"self parameter is not allowed in a constructor"}},
+		{"function", "fn f(mut self) {}", []string{"self parameter is only allowed in methods"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "struct P {\n\tx i32\n};\n\n" + tt.decl + "\n\nfn main() {}\n"
+			checkMessages(t, checkDiagnostics(t, src), tt.errs)
+		})
+	}
+}
+
+func TestCheck_Inference(t *testing.T) {
+	tests := []struct {
+		expr string
+		typ  string
+		err  string
+	}{
+		{"1", "i32", ""},
+		{"1 + 2 * 3", "i32", ""},
+		{"0xffff_ffff", "i32", "constant 4294967295 overflows i32"},
+		{"u8(1)", "u8", ""},
+		{"f()", "u8", ""},
+		{"f() + 1", "u8", ""},
+		{"1 < 2", "bool", ""},
+		{"P{x: 1}", "P", ""},
+		{"&v", "*i64", ""},
+		{"&mut m", "*mut i64", ""},
+		{"*(&v)", "i64", ""},
+		{"P{x: 1}.x", "i64", ""},
+		{"null", "", "cannot infer the type of x from null"},
+		{"noop()", "", "expression has no value"},
+		{"y", "", "undefined: y"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			src := `struct P {
+	x i64
+};
+
+fn f() -> u8 {
+	return 1;
+}
+
+fn noop() {}
+
+fn main() {
+	let v: i64 = 1;
+	let mut m: i64 = 1;
+	let x = ` + tt.expr + `;
+}
+`
+			file, err := syntax.Parse(lex.NewScanner("test.nv", []byte(src)))
+			if err != nil {
+				t.Fatalf("parse: %s", err)
+			}
+			info, err := Check(file)
+			if tt.err != "" {
+				var list diag.List
+				if !errors.As(err, &list) || len(list) != 1 || list[0].Message != tt.err {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("check: %s", err)
+			}
+
+			body := file.Decls[len(file.Decls)-1].(*syntax.FuncDecl).Body
+			decl := body.List[2].(*syntax.DeclStmt).Decl.(*syntax.VarDecl)
+			if got := info.Defs[decl.Name].Type.String(); got != tt.typ {
+				t.Errorf("got type %s, want %s", got, tt.typ)
+			}
+		})
+	}
+}
+
+func TestCheck_Conversions(t *testing.T) {
+	tests := []struct {
+		expr string
+		errs []string
+	}{
+		{"u8(x)", nil},
+		{"i64(x)", nil},
+		{"bool(x)", nil},
+		{"i32(b)", nil},
+		{"bool(b)", nil},
+		{"u8(300)", []string{"constant 300 overflows u8"}},
+		{"bool(300)", nil},
+		{"i32(p)", []string{"cannot convert P to i32"}},
+		{"bool(&x)", []string{"cannot convert *i32 to bool"}},
+		{"P(x)", []string{"cannot convert i32 to P"}},
+		{"i32(null)", []string{"cannot convert untyped null to i32"}},
+		{"i32(noop())", []string{"expression has no value"}},
+		{"i32()", []string{"conversion to i32 requires exactly one argument"}},
+		{"i32(x, x)", []string{"conversion to i32 requires exactly one argument"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			src := `struct P {
+	x i32
+};
+
+fn noop() {}
+
+fn main() {
+	let x: i32 = 1;
+	let b = x == 1;
+	let p = P{x: 1};
+	let y = ` + tt.expr + `;
+}
+`
+			checkMessages(t, checkDiagnostics(t, src), tt.errs)
+		})
+	}
+}
+
+func TestCheck_Labels(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		errs []string
+	}{
+		{"break outer", "outer: loop (c) {\n\t\tloop (c) {\n\t\t\tbreak outer;\n\t\t}\n\t}", nil},
+		{"continue outer", "outer: loop (c) {\n\t\tloop (c) {\n\t\t\tcontinue outer;\n\t\t}\n\t}", nil},
+		{"own label", "outer: loop (c) {\n\t\tbreak outer;\n\t}", nil},
+		// Labels are unique within a function.
+		{"reused", "a: loop (c) {\n\t\tbreak a;\n\t}\n\ta: loop (c) {\n\t\tcontinue a;\n\t}", []string{"label a already defined"}},
+		{"undefined", "loop (c) {\n\t\tbreak outer;\n\t}", []string{"label outer not defined"}},
+		{"not enclosing", "a: loop (c) {\n\t}\n\tloop (c) {\n\t\tcontinue a;\n\t}", []string{"invalid continue label a"}},
+		{"redefined", "a: loop (c) {\n\t\ta: loop (c) {\n\t\t}\n\t}", []string{"label a already defined"}},
+		{"break outside loop", "break;", []string{"break is not in a loop"}},
+		{"continue outside loop", "if (c) {\n\t\tcontinue;\n\t}", []string{"continue is not in a loop"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "fn f(c: bool) {\n\t" + tt.body + "\n}\n\nfn main() {}\n"
+			checkMessages(t, checkDiagnostics(t, src), tt.errs)
+		})
+	}
+}
+
+func TestCheck_Loops(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		errs []string
+	}{
+		{"infinite", "loop {\n\t\tbreak;\n\t}", nil},
+		{"for", "for (let mut i = 0; i < 10; i = i + 1) {\n\t\tlet x: i32 = i;\n\t}", nil},
+		{"for empty", "for (;;) {\n\t\tbreak;\n\t}", nil},
+		{"for scope", "for (let mut i = 0; i < 10; i = i + 1) {\n\t}\n\tlet x = i;", []string{"undefined: i"}},
+		{"for condition", "for (let mut i = 0; i; i = i + 1) {\n\t}", []string{"non-boolean condition in for statement"}},
+		{"range", "for i in 0..10 {\n\t\tlet x: i32 = i;\n\t}", nil},
+		{"range typed bound", "let n: u8 = 5;\n\tfor i in 0..n {\n\t\tlet x: u8 = i;\n\t}", nil},
+		{"range default type", "for i in 0..10 {\n\t\tlet x: u8 = i;\n\t}", []string{"cannot use value of type i32 as u8 value in variable declaration"}},
+		{"range mismatched", "let a: u8 = 1;\n\tlet b: i64 = 2;\n\tfor i in a..b {\n\t}", []string{"mismatched range bounds u8 and i64"}},
+		{"range bool", "for i in c..10 {\n\t}", []string{"cannot range over bool"}},
+		{"range immutable", "for i in 0..10 {\n\t\ti = 2;\n\t}", []string{"cannot assign to immutable variable i"}},
+		{"range scope", "for i in 0..10 {\n\t}\n\tlet x = i;", []string{"undefined: i"}},
+		{"labeled for", "outer: for i in 0..10 {\n\t\tfor (;;) {\n\t\t\tcontinue outer;\n\t\t}\n\t}", nil},
+		{"moved in range", "let a = R{n: 1};\n\tfor i in 0..2 {\n\t\tconsume(a);\n\t}", []string{"use of moved value: a"}},
+		{"moved in for", "let a = R{n: 1};\n\tfor (;;) {\n\t\tconsume(a);\n\t}", []string{"use of moved value: a"}},
+		{"moved before break", "let a = R{n: 1};\n\tloop {\n\t\tconsume(a);\n\t\tbreak;\n\t}\n\tconsume(a);", []string{"use of moved value: a"}},
+		{"moved in post", "let a = R{n: 1};\n\tfor (; c; consume(a)) {\n\t}", []string{"use of moved value: a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `struct R {
+	n i32
+};
+
+fn R::delete() {}
+
+fn consume(r: R) {}
+
+fn f(c: bool) {
+	` + tt.body + `
+}
+
+fn main() {}
+`
+			checkMessages(t, checkDiagnostics(t, src), tt.errs)
+		})
+	}
+}
+
+func TestCheck_Operators(t *testing.T) {
+	tests := []struct {
+		stmt string
+		errs []string
+	}{
+		{"x += 1;", nil},
+		{"x -= x * 2;", nil},
+		{"x |= x & ~x ^ 3;", nil},
+		{"x <<= n;", nil},
+		{"x >>= 2;", nil},
+		{"x++;", nil},
+		{"x--;", nil},
+		{"let y: u64 = 1 << n;", nil},
+		{"let y = 1 << n;\n\tlet z: i32 = y;", nil},
+		{"x += u;", []string{"cannot use value of type u8 as i64 value in assignment"}},
+		{"x /= 0;", []string{"division by zero"}},
+		{"x %= 0;", []string{"division by zero"}},
+		{"x <<= b;", []string{"invalid shift count of type bool"}},
+		{"x <<= -1;", []string{"invalid negative shift count -1"}},
+		{"c += 1;", []string{"cannot assign to immutable variable c"}},
+		{"c++;", []string{"cannot assign to immutable variable c"}},
+		{"b++;", []string{"operator ++ not defined on bool"}},
+		{"b--;", []string{"operator -- not defined on bool"}},
+		{"b += 1;", []string{"operator += not defined on bool"}},
+		{"b <<= 1;", []string{"operator <<= not defined on bool"}},
+		{"g()++;", []string{"cannot assign to expression"}},
+		{"let y = x & b;", []string{"mismatched types i64 and bool"}},
+		{"let y = b << 1;", []string{"operator << not defined on bool"}},
+		{"let y = ~b;", []string{"operator ~ not defined on bool"}},
+		{"let y: u8 = 256 << n;", []string{"constant 256 overflows u8"}},
+		{"let y: bool = 1 << n;", []string{"cannot use integer constant as bool value in variable declaration"}},
+		{"let y: u64 = 1 << b;", []string{"invalid shift count of type bool"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.stmt, func(t *testing.T) {
+			src := `fn g() -> i64 {
+	return 0;
+}
+
+fn f(n: u32, u: u8) {
+	let mut x: i64 = 0;
+	let mut b = x == 0;
+	let c: i64 = 0;
+	` + tt.stmt + `
+}
+
+fn main() {}
+`
+			checkMessages(t, checkDiagnostics(t, src), tt.errs)
+		})
+	}
+}
+
+func TestCheck_ControlFlow(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		errs []string
+	}{
+		{"return", "return 1;", nil},
+		{"missing return", "let x = 1;", []string{"missing return"}},
+		{"if without else", "if (c) {\n\t\treturn 1;\n\t}", []string{"missing return"}},
+		{"if else", "if (c) {\n\t\treturn 1;\n\t} else {\n\t\treturn 2;\n\t}", nil},
+		{"infinite loop", "loop {\n\t}", nil},
+		{"infinite loop with break", "loop {\n\t\tbreak;\n\t}", []string{"missing return"}},
+		{"conditional loop", "loop (c) {\n\t\treturn 1;\n\t}", []string{"missing return"}},
+		{"for without condition", "for (;;) {\n\t\treturn 1;\n\t}", nil},
+		{"range loop", "for i in 0..2 {\n\t\treturn i;\n\t}", []string{"missing return"}},
+		{"break inner loop", "loop {\n\t\tloop {\n\t\t\tbreak;\n\t\t}\n\t}", nil},
+		{"break outer loop", "outer: loop {\n\t\tloop {\n\t\t\tbreak outer;\n\t\t}\n\t}", []string{"missing return"}},
+		{"after return", "return 1;\n\treturn 2;", []string{"unreachable code"}},
+		{"reported once", "return 1;\n\tlet x = 1;\n\tlet y = 2;", []string{"unreachable code"}},
+		{"after break", "loop {\n\t\tbreak;\n\t\tlet x = 1;\n\t}\n\treturn 1;", []string{"unreachable code"}},
+		{"after continue", "loop (c) {\n\t\tcontinue;\n\t\tlet x = 1;\n\t}\n\treturn 1;", []string{"unreachable code"}},
+		{"after infinite loop", "loop {\n\t}\n\treturn 1;", []string{"unreachable code"}},
+		{"in branch", "if (c) {\n\t\treturn 1;\n\t\tlet x = 1;\n\t}\n\treturn 2;", []string{"unreachable code"}},
+		{"both branches return", "if (c) {\n\t\treturn 1;\n\t} else {\n\t\treturn 2;\n\t}\n\treturn 3;", []string{"unreachable code"}},
+		// A misplaced break or continue is an error, but doesn't make the
+		// following code unreachable.
+		{"break outside loop", "break;\n\treturn 1;", []string{"break is not in a loop"}},
+		{"continue outside loop", "continue;\n\treturn 1;", []string{"continue is not in a loop"}},
+		{"invalid label", "a: loop (c) {\n\t}\n\tloop {\n\t\tbreak a;\n\t}", []string{"invalid break label a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "fn f(c: bool) -> i32 {\n\t" + tt.body + "\n}\n\nfn main() {}\n"
+			checkMessages(t, checkDiagnostics(t, src), tt.errs)
+		})
+	}
+
+	src := `fn f() -> i32 {
+	return 1;
+	return 2;
+}
+
+fn g(c: bool) -> i32 {
+	if (c) {
+		return 1;
+	}
+}
+
+fn main() {}
+`
+	checkErrors(t, checkDiagnostics(t, src), []string{
+		"test.nv:3:2: warning: unreachable code",
+		"test.nv:10:1: missing return",
+	})
+}
+
+func TestCheck_Returns(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		errs []string
+	}{
+		{"bare return", "fn f() {\n\treturn;\n}\n", nil},
+		{"no return", "fn f() {}\n", nil},
+		{"early bare return", "fn f(c: bool) {\n\tif (c) {\n\t\treturn;\n\t}\n\tlet x = 1;\n}\n", nil},
+		{"missing value", "fn f() -> i32 {\n\treturn;\n}\n", []string{"missing return value"}},
+		{"too many values", "fn f() {\n\treturn 1;\n}\n", []string{"too many return values"}},
+		{"unreachable", "fn f() {\n\treturn;\n\tlet x = 1;\n}\n", []string{"unreachable code"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := tt.src + "\nfn main() {\n\treturn;\n}\n"
+			checkMessages(t, checkDiagnostics(t, src), tt.errs)
+		})
+	}
+}
+
+func TestCheck_BoolLiterals(t *testing.T) {
+	tests := []struct {
+		stmt string
+		errs []string
+	}{
+		{"let b = true;\n\tlet c: bool = b;", nil},
+		{"let b: bool = false;", nil},
+		{"let b = true && !false;", nil},
+		{"let b = x == true;", nil},
+		{"if (true) {\n\t}", nil},
+		{"let n = i32(true);", nil},
+		{"let n: i32 = true;", []string{"cannot use value of type bool as i32 value in variable declaration"}},
+		{"let n = true + 1;", []string{"mismatched types bool and untyped int"}},
+		{"let b = true < false;", []string{"operator < not defined on bool"}},
+		{"let b = -true;", []string{"operator - not defined on bool"}},
+		{"true = false;", []string{"cannot assign to expression"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.stmt, func(t *testing.T) {
+			src := "fn main() {\n\tlet x = false;\n\t" + tt.stmt + "\n}\n"
+			checkMessages(t, checkDiagnostics(t, src), tt.errs)
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package types
+
+import (
+	"math/big"
+
+	"github.com/andydunstall/nova/pkg/lex"
+	"github.com/andydunstall/nova/pkg/syntax"
+)
+
+// constValue evaluates the untyped constant expression exactly, or returns
+// nil if the expression isn't constant. The values of the operands must
+// already be recorded in [Info.Values].
+func (c *checker) constValue(expr syntax.Expr) *big.Int {
+	switch expr := expr.(type) {
+	case *syntax.BasicLitExpr:
+		if expr.Kind != lex.INT {
+			return nil
+		}
+		v, err := lex.IntValue(expr.Value)
+		if err != nil {
+			return nil
+		}
+		return v
+	case *syntax.UnaryExpr:
+		x := c.info.Values[expr.Expr]
+		if x == nil {
+			return nil
+		}
+		switch expr.Op {
+		case lex.SUB:
+			return new(big.Int).Neg(x)
+		case lex.TILDE:
+			// The complement of an untyped constant is -x-1.
+			return new(big.Int).Not(x)
+		}
+	case *syntax.BinaryExpr:
+		x, y := c.info.Values[expr.L], c.info.Values[expr.R]
+		if x == nil || y == nil {
+			return nil
+		}
+		switch expr.Op {
+		case lex.ADD:
+			return new(big.Int).Add(x, y)
+		case lex.SUB:
+			return new(big.Int).Sub(x, y)
+		case lex.MUL:
+			return new(big.Int).Mul(x, y)
+		case lex.QUO:
+			if y.Sign() == 0 {
+				return nil
+			}
+			// Truncated division, matching the generated code.
+			return new(big.Int).Quo(x, y)
+		case lex.REM:
+			if y.Sign() == 0 {
+				return nil
+			}
+			return new(big.Int).Rem(x, y)
+		case lex.AND:
+			return new(big.Int).And(x, y)
+		case lex.OR:
+			return new(big.Int).Or(x, y)
+		case lex.XOR:
+			return new(big.Int).Xor(x, y)
+		case lex.SHL:
+			if y.Sign() < 0 || y.Cmp(big.NewInt(maxShift)) > 0 {
+				return nil
+			}
+			return new(big.Int).Lsh(x, uint(y.Uint64()))
+		case lex.SHR:
+			if y.Sign() < 0 || y.Cmp(big.NewInt(maxShift)) > 0 {
+				return nil
+			}
+			// An arithmetic shift, so negative constants stay negative.
+			return new(big.Int).Rsh(x, uint(y.Uint64()))
+		}
+	}
+	return nil
+}
+
+// representable returns whether the value fits in the integer type.
+func representable(v *big.Int, t Primative) bool {
+	min, max := bounds(t)
+	return v.Cmp(min) >= 0 && v.Cmp(max) <= 0
+}
+
+// bounds returns the minimum and maximum values of the integer type.
+func bounds(t Primative) (*big.Int, *big.Int) {
+	var bits uint
+	switch t {
+	case U8, I8:
+		bits = 8
+	case U16, I16:
+		bits = 16
+	case U32, I32:
+		bits = 32
+	default:
+		bits = 64
+	}
+
+	one := big.NewInt(1)
+	if t.IsSigned() {
+		max := new(big.Int).Lsh(one, bits-1)
+		min := new(big.Int).Neg(max)
+		return min, max.Sub(max, one)
+	}
+	max := new(big.Int).Lsh(one, bits)
+	return new(big.Int), max.Sub(max, one)
+}
@@ -0,0 +1,151 @@
+package types
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/andydunstall/nova/pkg/diag"
+	"github.com/andydunstall/nova/pkg/lex"
+	"github.com/andydunstall/nova/pkg/syntax"
+)
+
+func TestConstValue(t *testing.T) {
+	tests := []struct {
+		typ  string
+		expr string
+		want string
+	}{
+		{"i32", "1 + 2 * 3", "7"},
+		{"i32", "(1 + 2) * 3", "9"},
+		{"i32", "-7 / 2", "-3"},
+		{"i32", "-7 % 2", "-1"},
+		{"i32", "7 % -2", "1"},
+		{"i32", "~0", "-1"},
+		{"i32", "~5", "-6"},
+		{"u8", "~0 & 0xff", "255"},
+		{"i32", "-8 >> 1", "-4"},
+		{"i32", "-1 >> 100", "-1"},
+		{"i32", "6 & 3 | 8 ^ 1", "11"},
+		{"u64", "1 << 63", "9223372036854775808"},
+		{"i64", "-(1 << 63)", "-9223372036854775808"},
+		{"u8", "(1 << 100) >> 93", "128"},
+		{"u64", "9223372036854775807 + 1", "9223372036854775808"},
+		{"u64", "0xffff_ffff_ffff_ffff", "18446744073709551615"},
+		{"i64", "-9223372036854775807 - 1", "-9223372036854775808"},
+		{"u8", "-1 + 2", "1"},
+		// Intermediate values are exact, even beyond 64 bits.
+		{"u8", "340282366920938463463374607431768211456 / 2658455991569831745807614120560689152", "128"},
+		{"i64", "18446744073709551616 - 18446744073709551615", "1"},
+		{"u8", "200 + 100 - 45", "255"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			info, decl, err := checkVarDecl(tt.typ, tt.expr)
+			if err != nil {
+				t.Fatalf("%s: %s", tt.expr, err)
+			}
+			v := info.ValueOf(decl.Expr)
+			if v == nil {
+				t.Fatalf("%s: not constant", tt.expr)
+			}
+			if v.String() != tt.want {
+				t.Errorf("%s: got %s, want %s", tt.expr, v, tt.want)
+			}
+		})
+	}
+}
+
+func TestConstValue_NotConstant(t *testing.T) {
+	info, decl, err := checkVarDecl("i32", "1 + f()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := info.ValueOf(decl.Expr); v != nil {
+		t.Errorf("got value %s, want nil", v)
+	}
+}
+
+func TestConstValue_Errors(t *testing.T) {
+	tests := []struct {
+		typ  string
+		expr string
+		err  string
+	}{
+		{"u8", "200 + 100", "constant 300 overflows u8"},
+		{"u8", "-1", "constant -1 overflows u8"},
+		{"i8", "128", "constant 128 overflows i8"},
+		{"i8", "-129", "constant -129 overflows i8"},
+		{"i32", "2147483647 + 1", "constant 2147483648 overflows i32"},
+		{"u64", "18446744073709551615 + 1", "constant 18446744073709551616 overflows u64"},
+		{"i64", "1 / 0", "division by zero"},
+		{"i64", "1 % (2 - 2)", "division by zero"},
+		{"i32", "1 << 31", "constant 2147483648 overflows i32"},
+		{"u64", "1 << 64", "constant 18446744073709551616 overflows u64"},
+		{"i64", "1 << -1", "invalid negative shift count -1"},
+		{"i64", "1 << 1024", "shift count 1024 too large"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, _, err := checkVarDecl(tt.typ, tt.expr)
+			var list diag.List
+			if !errors.As(err, &list) || len(list) == 0 {
+				t.Fatalf("%s: got err %v, want %q", tt.expr, err, tt.err)
+			}
+			if list[0].Message != tt.err {
+				t.Errorf("%s: got %q, want %q", tt.expr, list[0].Message, tt.err)
+			}
+		})
+	}
+}
+
+func TestRepresentable(t *testing.T) {
+	tests := []struct {
+		typ      Primative
+		min, max string
+	}{
+		{U8, "0", "255"},
+		{I8, "-128", "127"},
+		{U16, "0", "65535"},
+		{I16, "-32768", "32767"},
+		{U32, "0", "4294967295"},
+		{I32, "-2147483648", "2147483647"},
+		{U64, "0", "18446744073709551615"},
+		{I64, "-9223372036854775808", "9223372036854775807"},
+	}
+	one := big.NewInt(1)
+	for _, tt := range tests {
+		t.Run(tt.typ.String(), func(t *testing.T) {
+			min, _ := new(big.Int).SetString(tt.min, 10)
+			max, _ := new(big.Int).SetString(tt.max, 10)
+			if !representable(min, tt.typ) {
+				t.Errorf("%s not representable in %s", min, tt.typ)
+			}
+			if !representable(max, tt.typ) {
+				t.Errorf("%s not representable in %s", max, tt.typ)
+			}
+			if below := new(big.Int).Sub(min, one); representable(below, tt.typ) {
+				t.Errorf("%s representable in %s", below, tt.typ)
+			}
+			if above := new(big.Int).Add(max, one); representable(above, tt.typ) {
+				t.Errorf("%s representable in %s", above, tt.typ)
+			}
+		})
+	}
+}
+
+// checkVarDecl checks a function declaring a variable of the given type
+// initialized with the expression, and returns the declaration.
+func checkVarDecl(typ string, expr string) (*Info, *syntax.VarDecl, error) {
+	src := "fn f() -> i32 {\n\treturn 0;\n}\n\nfn main() {\n\tlet a: " + typ + " = " + expr + ";\n}\n"
+	file, err := syntax.Parse(lex.NewScanner("test.nv", []byte(src)))
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := Check(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	body := file.Decls[1].(*syntax.FuncDecl).Body
+	return info, body.List[0].(*syntax.DeclStmt).Decl.(*syntax.VarDecl), nil
+}
@@ -0,0 +1,888 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/andydunstall/nova/pkg/assert"
+	"github.com/andydunstall/nova/pkg/diag"
+	"github.com/andydunstall/nova/pkg/lex"
+	"github.com/andydunstall/nova/pkg/syntax"
+)
+
+// checkExpr checks the expression, then records and returns its type.
+//
+// Integer constants have type [UntypedInt] until they're converted to a
+// typed integer by their context, such as with [checker.assignable]. Their
+// exact value is recorded in [Info.Values].
+func (c *checker) checkExpr(expr syntax.Expr) Type {
+	typ := c.exprType(expr)
+	c.info.Types[expr] = typ
+	if typ == UntypedInt {
+		if v := c.constValue(expr); v != nil {
+			c.info.Values[expr] = v
+		}
+	}
+	return typ
+}
+
+func (c *checker) exprType(expr syntax.Expr) Type {
+	switch expr := expr.(type) {
+	case *syntax.BasicLitExpr:
+		return c.checkBasicLitExpr(expr)
+	case *syntax.VarExpr:
+		typ := c.checkVarExpr(expr)
+		if obj := c.info.Uses[expr.Name]; obj != nil && obj.Kind == Var {
+			c.checkUse(expr, obj)
+		}
+		return typ
+	case *syntax.AssignExpr:
+		return c.checkAssignExpr(expr)
+	case *syntax.UnaryExpr:
+		return c.checkUnaryExpr(expr)
+	case *syntax.BinaryExpr:
+		return c.checkBinaryExpr(expr)
+	case *syntax.CallExpr:
+		return c.checkCallExpr(expr)
+	case *syntax.CompositeLit:
+		return c.checkCompositeLit(expr)
+	case *syntax.SelectorExpr:
+		return c.checkSelectorExpr(expr)
+	default:
+		assert.Panicf("unsupported expr type: %#v", expr)
+		return nil // Unreachable.
+	}
+}
+
+func (c *checker) checkBasicLitExpr(expr *syntax.BasicLitExpr) Type {
+	switch expr.Kind {
+	case lex.INT:
+		return UntypedInt
+	case lex.BOOL:
+		return Bool
+	case lex.NULL:
+		return UntypedNull
+	default:
+		assert.Panicf("unsupported literal kind: %s", expr.Kind)
+		return nil // Unreachable.
+	}
+}
+
+func (c *checker) checkVarExpr(expr *syntax.VarExpr) Type {
+	obj := c.resolve(expr.Name)
+	if obj == nil {
+		return Invalid
+	}
+
+	switch obj.Kind {
+	case Var:
+		return obj.Type
+	case FuncObj:
+		c.errorf(expr, "cannot use function %s as a value", obj.Name)
+		return Invalid
+	default:
+		c.errorf(expr, "%s is a type, not an expression", obj.Name)
+		return Invalid
+	}
+}
+
+func (c *checker) checkAssignExpr(expr *syntax.AssignExpr) Type {
+	if expr.Op != lex.ILLEGAL {
+		return c.checkCompoundAssign(expr)
+	}
+
+	var typ Type = Invalid
+	if v, ok := expr.L.(*syntax.VarExpr); ok {
+		// Assigning to a variable doesn't use its value, so the variable
+		// may have been moved.
+		typ = c.checkVarExpr(v)
+		c.info.Types[v] = typ
+	} else {
+		typ = c.checkExpr(expr.L)
+		if !isInvalid(typ) && !c.addressable(expr.L) {
+			c.defaultUntyped(expr.L, typ)
+			c.errorf(expr.L, "cannot assign to expression").
+				WithLabel("not a variable or field")
+			typ = Invalid
+		}
+	}
+
+	if !isInvalid(typ) {
+		c.checkMutable(expr.L, expr.L, "assign to")
+	}
+
+	rt := c.checkExpr(expr.R)
+	c.assignable(expr.R, rt, typ, "assignment")
+	c.consume(expr.R)
+
+	// Assigning to a moved variable reinitialises it.
+	if v, ok := expr.L.(*syntax.VarExpr); ok {
+		if obj := c.info.Uses[v.Name]; obj != nil {
+			c.recordMove(moveInit, obj, nil)
+		}
+	}
+	return typ
+}
+
+// checkCompoundAssign checks an assignment such as 'x += y', which applies
+// the operator to the target and value, then assigns the result to the
+// target.
+func (c *checker) checkCompoundAssign(expr *syntax.AssignExpr) Type {
+	// Unlike a simple assignment, the target is used.
+	typ := c.checkExpr(expr.L)
+	if typ == nil {
+		c.errorNoValue(expr.L)
+		typ = Invalid
+	}
+	if !isInvalid(typ) && !c.addressable(expr.L) {
+		c.defaultUntyped(expr.L, typ)
+		c.errorf(expr.L, "cannot assign to expression").
+			WithLabel("not a variable or field")
+		typ = Invalid
+	}
+	if !isInvalid(typ) {
+		c.checkMutable(expr.L, expr.L, "assign to")
+		if !isInteger(typ) {
+			c.errorf(expr.L, "operator %s not defined on %s", expr.Tok, typ).
+				WithLabel("expected an integer")
+			typ = Invalid
+		}
+	}
+
+	if expr.Op == lex.SHL || expr.Op == lex.SHR {
+		c.checkShiftCount(expr.R)
+		return typ
+	}
+	rt := c.checkExpr(expr.R)
+	c.assignable(expr.R, rt, typ, "assignment")
+	c.checkDivisor(expr.Op, expr.R)
+	return typ
+}
+
+func (c *checker) checkUnaryExpr(expr *syntax.UnaryExpr) Type {
+	typ := c.checkExpr(expr.Expr)
+	if isInvalid(typ) {
+		return Invalid
+	}
+	if typ == nil {
+		c.errorNoValue(expr.Expr)
+		return Invalid
+	}
+
+	switch expr.Op {
+	case lex.AND:
+		if !c.addressable(expr.Expr) {
+			c.defaultUntyped(expr.Expr, typ)
+			c.errorf(expr.Expr, "cannot take the address of %s", describe(expr.Expr)).
+				WithLabel("not a variable or field")
+			return Invalid
+		}
+		if expr.Mut {
+			c.checkMutable(expr, expr.Expr, "take a mutable pointer to")
+		}
+		return &Pointer{Mut: expr.Mut, Elem: typ}
+	case lex.MUL:
+		p, ok := typ.(*Pointer)
+		if !ok {
+			c.defaultUntyped(expr.Expr, typ)
+			c.errorf(expr, "cannot dereference %s", typeString(c.info.Types[expr.Expr])).
+				WithLabel("not a pointer")
+			return Invalid
+		}
+		return p.Elem
+	case lex.SUB, lex.TILDE:
+		if !isInteger(typ) {
+			c.errorf(expr, "operator %s not defined on %s", expr.Op, typ)
+			return Invalid
+		}
+		return typ
+	case lex.NOT:
+		if !isBool(typ) {
+			c.errorf(expr, "operator %s not defined on %s", expr.Op, typ)
+			return Invalid
+		}
+		return Bool
+	default:
+		assert.Panicf("unsupported unary operator: %s", expr.Op)
+		return nil // Unreachable.
+	}
+}
+
+func (c *checker) checkBinaryExpr(expr *syntax.BinaryExpr) Type {
+	switch expr.Op {
+	case lex.LAND, lex.LOR:
+		for _, operand := range []syntax.Expr{expr.L, expr.R} {
+			typ := c.checkExpr(operand)
+			if typ == nil {
+				c.errorNoValue(operand)
+			} else if !isInvalid(typ) && !isBool(typ) {
+				c.errorf(operand, "operator %s not defined on %s", expr.Op, typ).
+					WithLabel("expected bool")
+			}
+		}
+		return Bool
+
+	case lex.EQL, lex.NEQ, lex.LSS, lex.LEQ, lex.GTR, lex.GEQ:
+		typ := c.checkOperands(expr)
+		if isInvalid(typ) {
+			return Bool
+		}
+		comparable := isBool(typ) || isPointer(typ)
+		if !isInteger(typ) && (!comparable || (expr.Op != lex.EQL && expr.Op != lex.NEQ)) {
+			c.errorf(expr, "operator %s not defined on %s", expr.Op, typ)
+			return Bool
+		}
+		if isUntyped(typ) {
+			c.defaultUntyped(expr.L, typ)
+			c.defaultUntyped(expr.R, typ)
+		}
+		return Bool
+
+	case lex.ADD, lex.SUB, lex.MUL, lex.QUO, lex.REM, lex.AND, lex.OR, lex.XOR:
+		typ := c.checkOperands(expr)
+		if isInvalid(typ) {
+			return Invalid
+		}
+		if !isInteger(typ) {
+			c.errorf(expr, "operator %s not defined on %s", expr.Op, typ)
+			return Invalid
+		}
+		if !c.checkDivisor(expr.Op, expr.R) {
+			return Invalid
+		}
+		return typ
+
+	case lex.SHL, lex.SHR:
+		return c.checkShift(expr)
+
+	default:
+		c.errorf(expr, "unsupported operator: %s", expr.Op)
+		return Invalid
+	}
+}
+
+// checkDivisor reports an error if the operator is a division or remainder
+// and the divisor is the constant zero.
+func (c *checker) checkDivisor(op lex.Token, divisor syntax.Expr) bool {
+	if op != lex.QUO && op != lex.REM {
+		return true
+	}
+	if v := c.info.Values[divisor]; v != nil && v.Sign() == 0 {
+		c.errorf(divisor, "division by zero").
+			WithLabel("divisor is zero")
+		return false
+	}
+	return true
+}
+
+// checkShift checks a shift expression. The result has the type of the
+// left operand, which must be an integer. Shifting an integer constant by a
+// constant count gives a constant. Shifting an integer constant by a
+// non-constant count gives an untyped value that isn't constant, whose left
+// operand takes the type the context converts the shift to, as with
+// 'let x: u64 = 1 << n'.
+func (c *checker) checkShift(expr *syntax.BinaryExpr) Type {
+	typ := c.checkExpr(expr.L)
+	ok := c.checkShiftCount(expr.R)
+	if typ == nil {
+		c.errorNoValue(expr.L)
+		return Invalid
+	}
+	if isInvalid(typ) || !ok {
+		c.defaultUntyped(expr.L, typ)
+		return Invalid
+	}
+	if !isInteger(typ) {
+		c.defaultUntyped(expr.L, typ)
+		c.errorf(expr, "operator %s not defined on %s", expr.Op, typ)
+		return Invalid
+	}
+	return typ
+}
+
+// maxShift is the largest constant shift count, which bounds the size of
+// shifted constants.
+const maxShift = 1023
+
+// checkShiftCount checks the count of a shift, which can be any integer
+// type. A constant count can't be negative. Counts of at least the width of
+// the shifted type shift out all bits.
+func (c *checker) checkShiftCount(count syntax.Expr) bool {
+	typ := c.checkExpr(count)
+	if typ == nil {
+		c.errorNoValue(count)
+		return false
+	}
+	if isInvalid(typ) {
+		return false
+	}
+	if !isInteger(typ) {
+		c.defaultUntyped(count, typ)
+		c.errorf(count, "invalid shift count of type %s", typ).
+			WithLabel("expected an integer")
+		return false
+	}
+	if typ != UntypedInt {
+		return true
+	}
+
+	v := c.info.Values[count]
+	if v != nil && v.Sign() < 0 {
+		c.errorf(count, "invalid negative shift count %s", v).
+			WithLabel("shift count must not be negative")
+		c.setType(count, U64)
+		return false
+	}
+	if v != nil && v.Cmp(big.NewInt(maxShift)) > 0 {
+		c.errorf(count, "shift count %s too large", v).
+			WithLabel(fmt.Sprintf("must be at most %d", maxShift))
+		c.setType(count, U64)
+		return false
+	}
+	c.convertUntyped(count, U64)
+	return true
+}
+
+// checkOperands checks the operands of the binary expression have the same
+// type and returns that type. If only one operand is an integer constant,
+// the constant is converted to the type of the other operand.
+func (c *checker) checkOperands(expr *syntax.BinaryExpr) Type {
+	lt := c.checkExpr(expr.L)
+	rt := c.checkExpr(expr.R)
+	if isInvalid(lt) || isInvalid(rt) {
+		return Invalid
+	}
+	if lt == nil {
+		c.errorNoValue(expr.L)
+		return Invalid
+	}
+	if rt == nil {
+		c.errorNoValue(expr.R)
+		return Invalid
+	}
+
+	switch {
+	case lt == UntypedInt && !isUntyped(rt) && isInteger(rt):
+		c.convertUntyped(expr.L, rt)
+		return rt
+	case rt == UntypedInt && !isUntyped(lt) && isInteger(lt):
+		c.convertUntyped(expr.R, lt)
+		return lt
+	case lt == UntypedNull && isPointer(rt):
+		c.setType(expr.L, rt)
+		return rt
+	case rt == UntypedNull && isPointer(lt):
+		c.setType(expr.R, lt)
+		return lt
+	case isPointer(lt) && isPointer(rt) && Identical(lt.(*Pointer).Elem, rt.(*Pointer).Elem):
+		// Mutable and immutable pointers can be compared.
+		return &Pointer{Elem: lt.(*Pointer).Elem}
+	case Identical(lt, rt):
+		return lt
+	}
+
+	c.errorf(expr, "mismatched types %s and %s", lt, rt).
+		WithSecondary(diag.SpanOf(expr.L), "has type "+lt.String()).
+		WithSecondary(diag.SpanOf(expr.R), "has type "+rt.String())
+	return Invalid
+}
+
+func (c *checker) checkCallExpr(expr *syntax.CallExpr) Type {
+	if expr.Recv != nil {
+		return c.checkMethodCall(expr)
+	}
+	if expr.Type != nil {
+		return c.checkStaticCall(expr)
+	}
+
+	name := expr.Func.Name
+
+	obj := c.resolve(expr.Func)
+	if obj == nil {
+		c.checkExprs(expr.Args)
+		return Invalid
+	}
+
+	if obj.Kind == TypeName {
+		return c.checkConversion(expr, obj.Type)
+	}
+
+	fn, ok := obj.Type.(*Func)
+	if !ok || obj.Kind != FuncObj {
+		c.errorf(expr.Func, "cannot call non-function %s", name).
+			WithLabel("has type " + obj.Type.String())
+		c.checkExprs(expr.Args)
+		return Invalid
+	}
+
+	return c.checkArgs(expr, fn, name)
+}
+
+// checkMethodCall checks a call to a method of the receiver, such as
+// 's.sum()'.
+func (c *checker) checkMethodCall(expr *syntax.CallExpr) Type {
+	typ := c.checkExpr(expr.Recv)
+	if typ == nil {
+		c.errorNoValue(expr.Recv)
+	}
+	s, ok := deref(typ).(*Struct)
+	if !ok {
+		if typ != nil && !isInvalid(typ) {
+			c.defaultUntyped(expr.Recv, typ)
+			c.errorf(expr.Func, "%s has no method %s", typeString(c.info.Types[expr.Recv]), expr.Func.Name).
+				WithLabel("not a struct")
+		}
+		c.checkExprs(expr.Args)
+		return Invalid
+	}
+
+	obj := s.Method(expr.Func.Name)
+	if obj == nil {
+		c.errorf(expr.Func, "%s has no method %s", s, expr.Func.Name).
+			WithLabel("unknown method")
+		c.checkExprs(expr.Args)
+		return Invalid
+	}
+	c.info.Uses[expr.Func] = obj
+
+	fn := obj.Type.(*Func)
+	if obj.Name == "delete" && fn.Recv != nil {
+		c.errorf(expr.Func, "explicit destructor calls are not allowed").
+			WithLabel("destructor called here").
+			WithNote("the destructor is called when the value goes out of scope")
+	}
+	if fn.Recv != nil && fn.Recv.Mut {
+		// Temporaries can be modified by the method.
+		action := "call mutating method " + obj.Name + " on"
+		if p, ok := typ.(*Pointer); ok {
+			c.checkMutablePointer(expr, p, action)
+		} else if c.addressable(expr.Recv) {
+			c.checkMutable(expr, expr.Recv, action)
+		}
+	}
+	if fn.Recv == nil {
+		c.errorf(expr.Func, "%s::%s has no receiver", s, obj.Name).
+			WithLabel("not a method").
+			WithNote("call it as %s::%s()", s, obj.Name)
+	}
+	return c.checkArgs(expr, fn, s.Name+"::"+obj.Name)
+}
+
+// checkStaticCall checks a call to a member function of a type, such as
+// 'MyStruct::new()'.
+func (c *checker) checkStaticCall(expr *syntax.CallExpr) Type {
+	obj := c.resolve(expr.Type)
+	if obj == nil {
+		c.checkExprs(expr.Args)
+		return Invalid
+	}
+	s, ok := obj.Type.(*Struct)
+	if !ok || obj.Kind != TypeName {
+		c.errorf(expr.Type, "%s is not a struct type", obj.Name)
+		c.checkExprs(expr.Args)
+		return Invalid
+	}
+
+	method := s.Method(expr.Func.Name)
+	if method == nil {
+		c.errorf(expr.Func, "%s has no member function %s", s, expr.Func.Name).
+			WithLabel("unknown member function")
+		c.checkExprs(expr.Args)
+		return Invalid
+	}
+	c.info.Uses[expr.Func] = method
+
+	fn := method.Type.(*Func)
+	if fn.Recv != nil {
+		c.errorf(expr, "cannot call method %s::%s without a receiver", s, method.Name).
+			WithNote("call it as a method, such as x.%s()", method.Name)
+	}
+	return c.checkArgs(expr, fn, s.Name+"::"+method.Name)
+}
+
+// checkArgs checks the arguments of a call to fn, and returns the result
+// type.
+func (c *checker) checkArgs(expr *syntax.CallExpr, fn *Func, name string) Type {
+	for i, arg := range expr.Args {
+		typ := c.checkExpr(arg)
+		if i < len(fn.Params) {
+			c.assignable(arg, typ, fn.Params[i].Type, "argument to "+name)
+			c.consume(arg)
+		} else {
+			c.defaultUntyped(arg, typ)
+		}
+	}
+
+	if len(expr.Args) < len(fn.Params) {
+		c.errorf(expr, "not enough arguments in call to %s", name).
+			WithNote("have %s", c.argTypes(expr.Args)).
+			WithNote("want %s", paramTypes(fn.Params))
+	} else if len(expr.Args) > len(fn.Params) {
+		c.errorf(expr.Args[len(fn.Params)], "too many arguments in call to %s", name).
+			WithNote("have %s", c.argTypes(expr.Args)).
+			WithNote("want %s", paramTypes(fn.Params))
+	}
+
+	return fn.Return
+}
+
+func (c *checker) checkCompositeLit(expr *syntax.CompositeLit) Type {
+	var typ *Struct
+	if obj := c.resolve(expr.Type); obj != nil {
+		if s, ok := obj.Type.(*Struct); ok && obj.Kind == TypeName {
+			typ = s
+		} else {
+			c.errorf(expr.Type, "%s is not a struct type", obj.Name).
+				WithLabel("has type " + obj.Type.String())
+		}
+	}
+
+	seen := make(map[string]*syntax.FieldValue)
+	for _, fv := range expr.Fields {
+		valueType := c.checkExpr(fv.Value)
+		if typ == nil {
+			continue
+		}
+
+		field := typ.Field(fv.Name.Name)
+		if field == nil {
+			c.defaultUntyped(fv.Value, valueType)
+			c.errorf(fv.Name, "unknown field %s in struct literal of type %s", fv.Name.Name, typ).
+				WithLabel("unknown field")
+			continue
+		}
+		if prev, ok := seen[field.Name]; ok {
+			c.errorf(fv.Name, "duplicate field %s in struct literal", field.Name).
+				WithLabel("duplicate field").
+				WithSecondary(diag.SpanOf(prev.Name), "first initialised here")
+		}
+		seen[field.Name] = fv
+
+		c.assignable(fv.Value, valueType, field.Type, "struct literal")
+		c.consume(fv.Value)
+	}
+
+	if typ == nil {
+		return Invalid
+	}
+	return typ
+}
+
+func (c *checker) checkSelectorExpr(expr *syntax.SelectorExpr) Type {
+	typ := c.checkExpr(expr.X)
+	if isInvalid(typ) {
+		return Invalid
+	}
+	if typ == nil {
+		c.errorNoValue(expr.X)
+		return Invalid
+	}
+
+	s, ok := deref(typ).(*Struct)
+	if !ok {
+		c.defaultUntyped(expr.X, typ)
+		c.errorf(expr.Sel, "%s has no field %s", typeString(c.info.Types[expr.X]), expr.Sel.Name).
+			WithLabel("not a struct")
+		return Invalid
+	}
+	field := s.Field(expr.Sel.Name)
+	if field == nil && s.Method(expr.Sel.Name) != nil {
+		c.errorf(expr.Sel, "cannot use method %s::%s as a value", s, expr.Sel.Name).
+			WithLabel("method must be called")
+		return Invalid
+	}
+	if field == nil {
+		c.errorf(expr.Sel, "%s has no field %s", s, expr.Sel.Name).
+			WithLabel("unknown field")
+		return Invalid
+	}
+	return field.Type
+}
+
+// addressable returns whether the expression refers to a memory location
+// that can be assigned to, meaning a variable, a dereferenced pointer, or
+// a field of an addressable struct or a struct pointer.
+func (c *checker) addressable(expr syntax.Expr) bool {
+	switch expr := expr.(type) {
+	case *syntax.VarExpr:
+		obj := c.info.Uses[expr.Name]
+		return obj == nil || obj.Kind == Var
+	case *syntax.UnaryExpr:
+		return expr.Op == lex.MUL
+	case *syntax.SelectorExpr:
+		return isPointer(c.info.Types[expr.X]) || c.addressable(expr.X)
+	default:
+		return false
+	}
+}
+
+// checkMutable reports an error at node if the memory expr refers to can't
+// be modified, meaning it's an immutable variable, or it's behind an
+// immutable pointer.
+func (c *checker) checkMutable(node syntax.Node, expr syntax.Expr, action string) {
+	switch expr := expr.(type) {
+	case *syntax.VarExpr:
+		obj := c.info.Uses[expr.Name]
+		if obj == nil || obj.Kind != Var {
+			return
+		}
+		if obj.Mut {
+			return
+		}
+		if c.fn != nil && obj == c.fn.Recv {
+			c.errorf(node, "cannot %s immutable receiver self", action).
+				WithLabel("cannot " + action + " self").
+				WithNote("declare the receiver as 'mut self' to modify it")
+			return
+		}
+		d := c.errorf(node, "cannot %s immutable variable %s", action, obj.Name).
+			WithLabel("cannot " + action + " " + obj.Name)
+		if obj.Ident != nil {
+			d.WithSecondary(diag.SpanOf(obj.Ident), "declared here; consider 'mut "+obj.Name+"'")
+		}
+	case *syntax.SelectorExpr:
+		if p, ok := c.info.Types[expr.X].(*Pointer); ok {
+			c.checkMutablePointer(node, p, action)
+			return
+		}
+		c.checkMutable(node, expr.X, action)
+	case *syntax.UnaryExpr:
+		if p, ok := c.info.Types[expr.Expr].(*Pointer); ok && expr.Op == lex.MUL {
+			c.checkMutablePointer(node, p, action)
+		}
+	}
+}
+
+func (c *checker) checkMutablePointer(node syntax.Node, p *Pointer, action string) {
+	if p.Mut {
+		return
+	}
+	c.errorf(node, "cannot %s a value behind an immutable pointer", action).
+		WithLabel("pointer has type "+p.String()).
+		WithNote("use a mutable pointer of type %s", &Pointer{Mut: true, Elem: p.Elem})
+}
+
+// deref returns the element type if the type is a pointer, so fields and
+// methods can be accessed through a pointer to a struct.
+func deref(t Type) Type {
+	if p, ok := t.(*Pointer); ok {
+		return p.Elem
+	}
+	return t
+}
+
+// describe returns a short description of the expression for error
+// messages.
+func describe(expr syntax.Expr) string {
+	switch expr.(type) {
+	case *syntax.BasicLitExpr:
+		return "a literal"
+	case *syntax.CallExpr:
+		return "a call result"
+	case *syntax.CompositeLit:
+		return "a struct literal"
+	default:
+		return "a temporary value"
+	}
+}
+
+// checkExprs checks expressions whose types aren't needed, such as the
+// arguments of an invalid call.
+func (c *checker) checkExprs(exprs []syntax.Expr) {
+	for _, expr := range exprs {
+		typ := c.checkExpr(expr)
+		c.defaultUntyped(expr, typ)
+	}
+}
+
+// resolve looks up the object the identifier refers to and records the
+// use. Reports an error and returns nil if the name isn't declared.
+func (c *checker) resolve(ident *syntax.Ident) *Object {
+	_, obj := c.scope.LookupParent(ident.Name)
+	if obj == nil {
+		c.errorf(ident, "undefined: %s", ident.Name).
+			WithLabel("not found in this scope")
+		return nil
+	}
+	c.info.Uses[ident] = obj
+	return obj
+}
+
+// checkConversion checks a call whose callee names a type, which converts
+// the argument to that type.
+//
+// Any integer or bool can be converted to any integer or bool type. An
+// integer converted to a smaller type is truncated, and to a larger type is
+// sign extended if the argument is signed, otherwise zero extended.
+// Converting to bool compares the value to zero, and a bool converts to 0
+// or 1. Integer constants must be representable in an integer target type.
+//
+// Otherwise the argument type must be identical to the target type.
+func (c *checker) checkConversion(expr *syntax.CallExpr, typ Type) Type {
+	for _, arg := range expr.Args {
+		c.checkExpr(arg)
+	}
+	if len(expr.Args) != 1 {
+		c.errorf(expr, "conversion to %s requires exactly one argument", typ)
+		return typ
+	}
+
+	arg := expr.Args[0]
+	argType := c.info.Types[arg]
+	switch {
+	case isInvalid(argType):
+	case argType == nil:
+		c.errorNoValue(arg)
+	case argType == UntypedNull && isPointer(typ):
+		c.setType(arg, typ)
+	case argType == UntypedInt && isInteger(typ):
+		c.convertUntyped(arg, typ)
+	case argType == UntypedInt && isBool(typ):
+		c.defaultUntyped(arg, argType)
+	case (isInteger(argType) || isBool(argType)) && (isInteger(typ) || isBool(typ)):
+	case Identical(argType, typ):
+		c.consume(arg)
+	default:
+		c.defaultUntyped(arg, argType)
+		c.errorf(expr, "cannot convert %s to %s", argType, typ).
+			WithLabel("invalid conversion")
+	}
+	return typ
+}
+
+// assignable checks a value of type typ can be assigned to a target of
+// type target, converting integer constants to the target type.
+func (c *checker) assignable(expr syntax.Expr, typ Type, target Type, context string) {
+	if isInvalid(typ) || isInvalid(target) {
+		return
+	}
+	if typ == nil {
+		c.errorNoValue(expr)
+		return
+	}
+
+	if typ == UntypedNull {
+		if isPointer(target) {
+			c.setType(expr, target)
+			return
+		}
+		c.errorf(expr, "cannot use null as %s value in %s", target, context).
+			WithLabel("expected " + target.String())
+		return
+	}
+	if isUntyped(typ) {
+		if isInteger(target) {
+			c.convertUntyped(expr, target)
+			return
+		}
+		c.errorf(expr, "cannot use integer constant as %s value in %s", target, context).
+			WithLabel("expected " + target.String())
+		return
+	}
+
+	// A mutable pointer can be used as an immutable pointer.
+	if p, ok := typ.(*Pointer); ok && p.Mut {
+		if q, ok := target.(*Pointer); ok && !q.Mut && Identical(p.Elem, q.Elem) {
+			return
+		}
+	}
+
+	if !Identical(typ, target) {
+		c.errorf(expr, "cannot use value of type %s as %s value in %s", typ, target, context).
+			WithLabel("expected " + target.String() + ", found " + typ.String())
+	}
+}
+
+// convertUntyped converts the untyped expression, and any untyped operands,
+// to the target type.
+func (c *checker) convertUntyped(expr syntax.Expr, target Type) {
+	if !isUntyped(c.info.Types[expr]) {
+		return
+	}
+
+	v := c.info.Values[expr]
+	if v == nil {
+		// An untyped expression that isn't constant, such as '1 << n', may
+		// still have constant operands that must fit the target.
+		c.info.Types[expr] = target
+		switch expr := expr.(type) {
+		case *syntax.UnaryExpr:
+			c.convertUntyped(expr.Expr, target)
+		case *syntax.BinaryExpr:
+			c.convertUntyped(expr.L, target)
+			if expr.Op != lex.SHL && expr.Op != lex.SHR {
+				c.convertUntyped(expr.R, target)
+			}
+		}
+		return
+	}
+	if p, ok := target.(Primative); ok && !representable(v, p) {
+		min, max := bounds(p)
+		c.errorf(expr, "constant %s overflows %s", v, p).
+			WithLabel(fmt.Sprintf("%s holds values from %s to %s", p, min, max))
+	}
+	c.setType(expr, target)
+}
+
+// setType sets the type of the untyped expression and its untyped operands.
+func (c *checker) setType(expr syntax.Expr, target Type) {
+	if !isUntyped(c.info.Types[expr]) {
+		return
+	}
+	c.info.Types[expr] = target
+
+	switch expr := expr.(type) {
+	case *syntax.UnaryExpr:
+		c.setType(expr.Expr, target)
+	case *syntax.BinaryExpr:
+		c.setType(expr.L, target)
+		// The shift count has its own type.
+		if expr.Op != lex.SHL && expr.Op != lex.SHR {
+			c.setType(expr.R, target)
+		}
+	}
+}
+
+// defaultUntyped converts an untyped expression without a type from its
+// context to its default type.
+func (c *checker) defaultUntyped(expr syntax.Expr, typ Type) Type {
+	// null has no default type.
+	if !isUntyped(typ) || typ == UntypedNull {
+		return typ
+	}
+	c.convertUntyped(expr, I32)
+	return I32
+}
+
+// errorNoValue reports using an expression without a value, such as a call
+// to a function without a return type.
+func (c *checker) errorNoValue(expr syntax.Expr) {
+	c.errorf(expr, "expression has no value").
+		WithLabel("used as value")
+}
+
+func (c *checker) argTypes(args []syntax.Expr) string {
+	var types []string
+	for _, arg := range args {
+		types = append(types, typeString(c.info.Types[arg]))
+	}
+	return "(" + strings.Join(types, ", ") + ")"
+}
+
+func paramTypes(params []*Object) string {
+	var types []string
+	for _, param := range params {
+		types = append(types, typeString(param.Type))
+	}
+	return "(" + strings.Join(types, ", ") + ")"
+}
+
+// typeString returns the name of the type, handling expressions without a
+// type.
+func typeString(t Type) string {
+	if t == nil {
+		return "no value"
+	}
+	return t.String()
+}
@@ -0,0 +1,192 @@
+package types
+
+import (
+	"maps"
+
+	"github.com/andydunstall/nova/pkg/cfg"
+	"github.com/andydunstall/nova/pkg/diag"
+	"github.com/andydunstall/nova/pkg/syntax"
+)
+
+// Values of types that need destroying are moved rather than copied, so the
+// value is only destroyed once. Using a variable after it may have been
+// moved on any path is an error.
+//
+// While checking a function, the checker records the uses, moves and
+// initialisations of such variables against the control flow graph node
+// being checked, such as a statement or loop condition. Once the body is
+// checked, checkMoves propagates the move state through the control flow
+// graph to find uses of moved variables.
+
+// moveKind is the kind of a recorded [move].
+type moveKind int
+
+const (
+	// moveUse is a use of the variable's value.
+	moveUse moveKind = iota
+	// moveOut moves the value out of the variable.
+	moveOut
+	// moveInit gives the variable a new value, by a declaration or an
+	// assignment.
+	moveInit
+)
+
+// move is a use, move or initialisation of a variable.
+type move struct {
+	kind moveKind
+	obj  *Object
+	// expr is the expression using or moving the variable, or nil for
+	// moveInit.
+	expr syntax.Expr
+}
+
+// flow is the move state at a point in a function.
+type flow struct {
+	// moved maps each variable that may have been moved to the expression
+	// that moved it.
+	moved map[*Object]syntax.Expr
+}
+
+func newFlow() *flow {
+	return &flow{moved: make(map[*Object]syntax.Expr)}
+}
+
+func (f *flow) copy() *flow {
+	return &flow{moved: maps.Clone(f.moved)}
+}
+
+// merge adds the variables moved in other to f, where control flow from
+// both merges, and reports whether f changed.
+func (f *flow) merge(other *flow) bool {
+	changed := false
+	for obj, expr := range other.moved {
+		if _, ok := f.moved[obj]; !ok {
+			f.moved[obj] = expr
+			changed = true
+		}
+	}
+	return changed
+}
+
+// checkUse records a use of the variable.
+func (c *checker) checkUse(expr *syntax.VarExpr, obj *Object) {
+	c.recordMove(moveUse, obj, expr)
+}
+
+// consume records that the value of the expression is moved, if its type
+// needs destroying. Only variables and temporaries can be moved.
+func (c *checker) consume(expr syntax.Expr) {
+	if !NeedsDestroy(c.info.Types[expr]) {
+		return
+	}
+
+	switch expr := expr.(type) {
+	case *syntax.VarExpr:
+		obj := c.info.Uses[expr.Name]
+		if obj == nil {
+			return
+		}
+		if c.fn != nil && obj == c.fn.Recv {
+			c.errorf(expr, "cannot move out of self").
+				WithLabel("self refers to the receiver")
+			return
+		}
+		c.recordMove(moveOut, obj, expr)
+	case *syntax.SelectorExpr:
+		c.errorf(expr, "cannot move out of field %s", expr.Sel.Name).
+			WithLabel("field of type " + c.info.Types[expr].String() + " has a destructor")
+	case *syntax.UnaryExpr:
+		c.errorf(expr, "cannot move out of a pointer").
+			WithLabel("value of type " + c.info.Types[expr].String() + " has a destructor")
+	case *syntax.AssignExpr:
+		c.errorf(expr, "cannot move out of assignment").
+			WithLabel("assignment of type " + c.info.Types[expr].String() + " has a destructor")
+	}
+}
+
+// recordMove records the move against the current control flow graph
+// node. Variables whose type doesn't need destroying are copied rather
+// than moved, so aren't recorded.
+func (c *checker) recordMove(kind moveKind, obj *Object, expr syntax.Expr) {
+	if c.moves == nil || !NeedsDestroy(obj.Type) {
+		return
+	}
+	c.moves[c.node] = append(c.moves[c.node], move{kind: kind, obj: obj, expr: expr})
+}
+
+// checkMoves reports uses of variables that may have been moved, by
+// propagating the move state through the control flow graph until the
+// state at the start of each block no longer changes. Blocks that can't be
+// reached are skipped.
+func (c *checker) checkMoves(g *cfg.CFG) {
+	entry := make([]*flow, len(g.Blocks))
+	entry[0] = newFlow()
+	for changed := true; changed; {
+		changed = false
+		for _, block := range g.Blocks {
+			if entry[block.Index] == nil {
+				continue
+			}
+			exit := c.transfer(block, entry[block.Index].copy(), false)
+			for _, succ := range block.Succs {
+				if entry[succ.Index] == nil {
+					entry[succ.Index] = newFlow()
+					changed = true
+				}
+				if entry[succ.Index].merge(exit) {
+					changed = true
+				}
+			}
+		}
+	}
+
+	for _, block := range g.Blocks {
+		if entry[block.Index] != nil {
+			c.transfer(block, entry[block.Index], true)
+		}
+	}
+}
+
+// transfer applies the moves recorded for the nodes of the block to the
+// move state at the start of the block, and returns the state at the end
+// of the block. If report is set, uses of moved variables are reported.
+func (c *checker) transfer(block *cfg.Block, f *flow, report bool) *flow {
+	for _, node := range block.Nodes {
+		for _, m := range c.moves[node] {
+			switch m.kind {
+			case moveUse:
+				moved, ok := f.moved[m.obj]
+				if !ok {
+					continue
+				}
+				if report {
+					c.errorMoved(m.expr, m.obj, moved)
+				}
+				// Only report the first use.
+				delete(f.moved, m.obj)
+			case moveOut:
+				f.moved[m.obj] = m.expr
+			case moveInit:
+				delete(f.moved, m.obj)
+			}
+		}
+	}
+	return f
+}
+
+// errorMoved reports a use of a variable that may have been moved by the
+// given expression. If the move doesn't come before the use, the value was
+// moved by a previous iteration of a loop.
+func (c *checker) errorMoved(use syntax.Expr, obj *Object, moved syntax.Expr) {
+	d := c.errorf(use, "use of moved value: %s", obj.Name)
+	switch {
+	case moved == use:
+		d.WithLabel("value moved here, in previous iteration of loop")
+	case moved.Pos().Offset > use.Pos().Offset:
+		d.WithLabel("value used here after move").
+			WithSecondary(diag.SpanOf(moved), "value moved here, in previous iteration of loop")
+	default:
+		d.WithLabel("value used here after move").
+			WithSecondary(diag.SpanOf(moved), "value moved here")
+	}
+}
@@ -1,15 +1,86 @@
 package types
 
 import (
+	"math/big"
+
+	"github.com/andydunstall/nova/pkg/cfg"
+	"github.com/andydunstall/nova/pkg/diag"
 	"github.com/andydunstall/nova/pkg/syntax"
 )
 
 type Info struct {
+	// Defs maps identifiers to the objects they define. The type of a
+	// variable declared without a type is its inferred type.
 	Defs map[*syntax.Ident]*Object
+
+	// Uses maps identifiers to the objects they refer to, such as the
+	// variable in a [syntax.VarExpr] or the function in a
+	// [syntax.CallExpr].
+	Uses map[*syntax.Ident]*Object
+
+	// Scopes maps the nodes that open a scope to that scope. The file scope
+	// is stored in FileScope.
+	//
+	// A [syntax.FuncDecl] scope contains the function parameters and the
+	// variables declared at the top level of the body. Nested
+	// [syntax.BlockStmt] and if statement branches have their own scope. A
+	// [syntax.ForStmt] scope contains the variables declared by its init
+	// statement, and a [syntax.RangeStmt] scope contains its variable.
+	Scopes map[syntax.Node]*Scope
+
+	// FileScope is the scope containing the top level declarations.
+	FileScope *Scope
+
+	// Types maps expressions to their types. Integer constants record the
+	// type they are converted to by their context.
+	//
+	// Calls to functions without a return type map to a nil type.
+	Types map[syntax.Expr]Type
+
+	// Values maps constant expressions to their exact values. A constant
+	// expression is built from integer literals and the unary and
+	// arithmetic operators, such as '200 + 100'.
+	//
+	// Values are representable in the type recorded in Types, unless an
+	// error was reported.
+	Values map[syntax.Expr]*big.Int
+
+	// CFGs maps function declarations to the control flow graphs of their
+	// bodies.
+	CFGs map[*syntax.FuncDecl]*cfg.CFG
+
+	// Warnings contains the warnings reported by the checker, such as
+	// unreachable code.
+	Warnings diag.List
 }
 
 func newInfo() *Info {
 	return &Info{
-		Defs: make(map[*syntax.Ident]*Object),
+		Defs:   make(map[*syntax.Ident]*Object),
+		Uses:   make(map[*syntax.Ident]*Object),
+		Scopes: make(map[syntax.Node]*Scope),
+		Types:  make(map[syntax.Expr]Type),
+		Values: make(map[syntax.Expr]*big.Int),
+		CFGs:   make(map[*syntax.FuncDecl]*cfg.CFG),
+	}
+}
+
+// TypeOf returns the type of the expression, or nil if unknown.
+func (info *Info) TypeOf(expr syntax.Expr) Type {
+	return info.Types[expr]
+}
+
+// ValueOf returns the value of the constant expression, or nil if the
+// expression isn't constant.
+func (info *Info) ValueOf(expr syntax.Expr) *big.Int {
+	return info.Values[expr]
+}
+
+// ObjectOf returns the object defined or used by the identifier, or nil if
+// unknown.
+func (info *Info) ObjectOf(ident *syntax.Ident) *Object {
+	if obj, ok := info.Defs[ident]; ok {
+		return obj
 	}
+	return info.Uses[ident]
 }
@@ -1,6 +1,38 @@
 package types
 
+import "github.com/andydunstall/nova/pkg/syntax"
+
+// ObjectKind is the kind of entity an object describes.
+type ObjectKind int
+
+const (
+	// Var is a local variable or function parameter.
+	Var ObjectKind = iota
+	// FuncObj is a function.
+	FuncObj
+	// TypeName is a named type.
+	TypeName
+)
+
+var objectKindStrs = [...]string{
+	Var:      "variable",
+	FuncObj:  "function",
+	TypeName: "type",
+}
+
+func (k ObjectKind) String() string {
+	return objectKindStrs[k]
+}
+
+// Object is a named entity, such as a variable, function or type.
 type Object struct {
+	Kind ObjectKind
 	Name string
 	Type Type
+	// Mut is set if the variable is mutable, such as 'let mut x'.
+	Mut bool
+
+	// Ident is the identifier that declared the object, or nil if the
+	// object is predeclared.
+	Ident *syntax.Ident
 }
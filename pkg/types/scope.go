@@ -0,0 +1,92 @@
+package types
+
+import (
+	"sort"
+)
+
+// Scope maps names to the objects declared in a block.
+//
+// Scopes form a tree, from the [Universe] scope containing predeclared
+// types, to the file scope containing functions, to function and block
+// scopes containing parameters and variables.
+type Scope struct {
+	parent   *Scope
+	children []*Scope
+
+	objs map[string]*Object
+}
+
+// NewScope returns a new scope nested in the parent scope.
+func NewScope(parent *Scope) *Scope {
+	s := &Scope{
+		parent: parent,
+		objs:   make(map[string]*Object),
+	}
+	if parent != nil {
+		parent.children = append(parent.children, s)
+	}
+	return s
+}
+
+// Parent returns the enclosing scope, or nil for the universe scope.
+func (s *Scope) Parent() *Scope {
+	return s.parent
+}
+
+// Children returns the scopes nested directly in this scope.
+func (s *Scope) Children() []*Scope {
+	return s.children
+}
+
+// Names returns the names declared in the scope in sorted order.
+func (s *Scope) Names() []string {
+	names := make([]string, 0, len(s.objs))
+	for name := range s.objs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the object with the given name declared in this scope,
+// or nil if not found. Enclosing scopes are not searched.
+func (s *Scope) Lookup(name string) *Object {
+	return s.objs[name]
+}
+
+// LookupParent searches this scope and its enclosing scopes for the object
+// with the given name. Returns the scope containing the object and the
+// object, or nil if not found.
+func (s *Scope) LookupParent(name string) (*Scope, *Object) {
+	for ; s != nil; s = s.parent {
+		if obj, ok := s.objs[name]; ok {
+			return s, obj
+		}
+	}
+	return nil, nil
+}
+
+// Insert declares the object in the scope. If the scope already contains an
+// object with the same name, Insert leaves the scope unchanged and returns
+// the existing object, otherwise it returns nil.
+func (s *Scope) Insert(obj *Object) *Object {
+	if existing, ok := s.objs[obj.Name]; ok {
+		return existing
+	}
+	s.objs[obj.Name] = obj
+	return nil
+}
+
+// Universe is the outermost scope, containing the predeclared types.
+var Universe *Scope
+
+func init() {
+	Universe = NewScope(nil)
+	for name, typ := range primatives {
+		Universe.Insert(&Object{
+			Kind: TypeName,
+			Name: name,
+			Type: typ,
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package types
+
+import "github.com/andydunstall/nova/pkg/assert"
+
+// Sizeof returns the size of a value of the type in bytes.
+func Sizeof(t Type) int {
+	switch t := t.(type) {
+	case Primative:
+		switch t {
+		case Bool, U8, I8:
+			return 1
+		case U16, I16:
+			return 2
+		case U32, I32:
+			return 4
+		case U64, I64:
+			return 8
+		case Invalid:
+			return 0
+		}
+	case *Pointer:
+		return 8
+	case *Struct:
+		return t.size
+	}
+	assert.Panicf("unsized type: %s", t)
+	return 0 // Unreachable.
+}
+
+// Alignof returns the alignment of a value of the type in bytes.
+func Alignof(t Type) int {
+	if s, ok := t.(*Struct); ok {
+		return s.align
+	}
+	return max(Sizeof(t), 1)
+}
+
+// layout computes the field offsets, size and alignment of the struct,
+// following the System V ABI. Fields of struct type must already have
+// their layout computed.
+func (t *Struct) layout() {
+	offset, align := 0, 1
+	for _, f := range t.Fields {
+		a := Alignof(f.Type)
+		offset = alignUp(offset, a)
+		f.Offset = offset
+		offset += Sizeof(f.Type)
+		align = max(align, a)
+	}
+	t.size = alignUp(offset, align)
+	t.align = align
+}
+
+func alignUp(n, align int) int {
+	return (n + align - 1) / align * align
+}
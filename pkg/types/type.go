@@ -20,6 +20,13 @@ const (
 	I32
 	U64
 	I64
+
+	// UntypedInt is the type of an integer constant that hasn't yet been
+	// converted to a typed integer by its context.
+	UntypedInt
+	// UntypedNull is the type of 'null' until it's converted to a pointer
+	// type by its context.
+	UntypedNull
 )
 
 func (t Primative) String() string {
@@ -28,6 +35,21 @@ func (t Primative) String() string {
 
 func (t Primative) typeImpl() {}
 
+// IsInteger returns whether the type is a typed or untyped integer.
+func (t Primative) IsInteger() bool {
+	return U8 <= t && t <= UntypedInt
+}
+
+// IsSigned returns whether the type is a signed integer.
+func (t Primative) IsSigned() bool {
+	switch t {
+	case I8, I16, I32, I64, UntypedInt:
+		return true
+	default:
+		return false
+	}
+}
+
 var primativeStrs = [...]string{
 	Invalid: "invalid",
 
@@ -40,28 +62,42 @@ var primativeStrs = [...]string{
 	I32:  "i32",
 	U64:  "u64",
 	I64:  "i64",
-}
 
-var primatives map[string]Primative
+	UntypedInt:  "untyped int",
+	UntypedNull: "untyped null",
+}
 
-func init() {
-	primatives = make(map[string]Primative, len(primativeStrs))
+// primatives maps the names of the primative types to their type.
+//
+// Initialised as a variable rather than in init so it's available when
+// initialising the [Universe].
+var primatives = func() map[string]Primative {
+	m := make(map[string]Primative, len(primativeStrs))
 	for i := 0; i != len(primativeStrs); i++ {
-		if Primative(i) == Invalid {
+		if Primative(i) == Invalid || Primative(i) == UntypedInt || Primative(i) == UntypedNull {
 			continue
 		}
-		primatives[primativeStrs[i]] = Primative(i)
+		m[primativeStrs[i]] = Primative(i)
 	}
-}
+	return m
+}()
 
 type Func struct {
+	// Recv is the implicit 'self' parameter of a method, or nil if the
+	// function isn't a method.
+	Recv   *Object
 	Params []*Object
 	Return Type
 }
 
 func (t Func) String() string {
 	s := "func("
-	// TODO(andydunstall): Params
+	for i, param := range t.Params {
+		if i > 0 {
+			s += ", "
+		}
+		s += param.Type.String()
+	}
 	s += ")"
 	if t.Return != nil {
 		s += " -> "
@@ -71,3 +107,138 @@ func (t Func) String() string {
 }
 
 func (t Func) typeImpl() {}
+
+// Pointer is a pointer to a value of type Elem. The value can only be
+// modified through a mutable pointer.
+type Pointer struct {
+	Mut  bool
+	Elem Type
+}
+
+func (t *Pointer) String() string {
+	if t.Mut {
+		return "*mut " + t.Elem.String()
+	}
+	return "*" + t.Elem.String()
+}
+
+func (t *Pointer) typeImpl() {}
+
+// Struct is a named struct type. Each struct declaration is a distinct
+// type.
+type Struct struct {
+	Name   string
+	Fields []*Field
+	// Methods are the member functions declared with 'fn Name::method',
+	// including static functions such as constructors.
+	Methods []*Object
+
+	// size and align are set once the layout is computed.
+	size  int
+	align int
+}
+
+// Field is a struct field.
+type Field struct {
+	Name string
+	Type Type
+	// Offset is the offset of the field from the start of the struct in
+	// bytes.
+	Offset int
+}
+
+func (t *Struct) String() string {
+	return t.Name
+}
+
+func (t *Struct) typeImpl() {}
+
+// Field returns the field with the given name, or nil if there is no such
+// field.
+func (t *Struct) Field(name string) *Field {
+	for _, f := range t.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// Method returns the member function with the given name, or nil if there
+// is no such function.
+func (t *Struct) Method(name string) *Object {
+	for _, m := range t.Methods {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// Destructor returns the 'delete' method of the struct, or nil if the
+// struct has no destructor.
+func (t *Struct) Destructor() *Object {
+	m := t.Method("delete")
+	if m == nil || m.Type.(*Func).Recv == nil {
+		return nil
+	}
+	return m
+}
+
+// NeedsDestroy returns whether values of the type must be destroyed when
+// they go out of scope, meaning the type or one of its fields has a
+// destructor. Such values are moved rather than copied.
+func NeedsDestroy(t Type) bool {
+	s, ok := t.(*Struct)
+	if !ok {
+		return false
+	}
+	if s.Destructor() != nil {
+		return true
+	}
+	for _, f := range s.Fields {
+		if NeedsDestroy(f.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// Identical returns whether the two types are identical.
+func Identical(a, b Type) bool {
+	if a, ok := a.(*Pointer); ok {
+		b, ok := b.(*Pointer)
+		return ok && a.Mut == b.Mut && Identical(a.Elem, b.Elem)
+	}
+	return a == b
+}
+
+// isInteger returns whether the type is a typed or untyped integer.
+func isInteger(t Type) bool {
+	p, ok := t.(Primative)
+	return ok && p.IsInteger()
+}
+
+func isUntyped(t Type) bool {
+	return t == UntypedInt || t == UntypedNull
+}
+
+func isPointer(t Type) bool {
+	_, ok := t.(*Pointer)
+	return ok
+}
+
+func isStruct(t Type) bool {
+	_, ok := t.(*Struct)
+	return ok
+}
+
+func isBool(t Type) bool {
+	return t == Bool
+}
+
+// isInvalid returns whether the type is invalid, meaning an error has
+// already been reported.
+func isInvalid(t Type) bool {
+	return t == Invalid
+}